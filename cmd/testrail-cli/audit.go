@@ -0,0 +1,49 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal"
+)
+
+// audit reports suite cases without tests and cases claimed by several tests,
+// it is written to --REPORT file or stdout
+func audit() {
+	t := initSuiteCases()
+	tObjects := convertTests(viper.GetString("FILE"))
+
+	// section names aren't in cases json export, section ids are shown instead
+	var sections map[int]string
+	if viper.GetString("CASES") == "" {
+		projectID, suiteID := t.Suite()
+		if projectID == 0 {
+			projectID, suiteID = viper.GetInt("PROJECT_ID"), viper.GetInt("SUITE_ID")
+		}
+		var err error
+		if sections, err = t.SectionNames(projectID, suiteID); err != nil {
+			log.Printf("%v, section ids are shown instead of names", err)
+		}
+	}
+
+	a := internal.NewAudit(t.GetCasesWithDescription(), sections, tObjects, t, time.Now())
+	a.Commit = internal.CurrentCommit()
+	log.Printf("%d of %d cases are covered by tests (%.2f%%), %d orphaned, %d claimed by several tests, %d not in suite",
+		a.Covered, a.Total, a.Coverage, len(a.Orphaned), len(a.Duplicates), len(a.Unknown))
+
+	if path := viper.GetString("REPORT"); path != "" {
+		writeReport(path, a)
+		return
+	}
+	if err := a.Write(os.Stdout, viper.GetString("REPORT-FORMAT")); err != nil {
+		log.Fatal(err)
+	}
+}
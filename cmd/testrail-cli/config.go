@@ -0,0 +1,26 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/config"
+)
+
+// showConfig prints effective settings and their sources, secrets are masked
+func showConfig(cfg config.Config) {
+	if sub := pflag.Arg(1); sub != "show" {
+		log.Fatalf("Unsupported config command %q, use config show", sub)
+	}
+	if err := cfg.Write(os.Stdout, cfg.Resolve(viper.GetViper(), pflag.CommandLine, "TR")); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// convert prints tests found in input by --FORMAT parser and --MATCHER converter as json,
+// testrail isn't used, so parser and converter settings could be checked
+func convert() {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(convertTests(viper.GetString("FILE"))); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal"
+	"github.com/insolar/testrail-cli/testrail"
+	"github.com/insolar/testrail-cli/types"
+)
+
+// createMissing adds cases for tests without case ID to sections mirroring package path
+// and prints t.Log lines to annotate tests with
+func createMissing(t *testrail.Uploader, notFound []*types.TestMatcher, dryRun bool) {
+	prefix := viper.GetString("PACKAGE-PREFIX")
+	if prefix == "" {
+		prefix = internal.ModulePath(".")
+	}
+	missing := internal.MissingCases(notFound, prefix)
+	if len(missing) == 0 {
+		return
+	}
+
+	if dryRun {
+		for _, c := range missing {
+			fmt.Printf("Would create case %q in section %q\n", c.Title, strings.Join(c.Section, "/"))
+		}
+		return
+	}
+
+	projectID, suiteID := viper.GetInt("PROJECT_ID"), viper.GetInt("SUITE_ID")
+	if runProject, runSuite := t.Suite(); runProject != 0 {
+		projectID, suiteID = runProject, runSuite
+	}
+	if projectID == 0 || suiteID == 0 {
+		log.Fatal("provide project and suite ids to create missing cases, ex.: --PROJECT_ID=3 --SUITE_ID=12")
+	}
+
+	var created []internal.MissingCase
+	for _, c := range missing {
+		sectionID, err := t.EnsureSection(projectID, suiteID, viper.GetInt("SECTION_ID"), c.Section)
+		if err != nil {
+			log.Printf("case for %s is not created: %v", c.Test, err)
+			continue
+		}
+		testCase, err := t.AddCase(sectionID, c.Title)
+		if err != nil {
+			log.Printf("case for %s is not created: %v", c.Test, err)
+			continue
+		}
+		c.CaseID = testCase.ID
+		created = append(created, c)
+	}
+
+	log.Printf("%d of %d missing cases are created, annotate tests with:", len(created), len(missing))
+	if err := internal.PrintAnnotations(os.Stdout, created); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+// Audit is drift between testrail suite cases and cases claimed by go tests,
+// lists are sorted, so audits of different runs could be diffed
+type Audit struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Commit      string            `json:"commit,omitempty"`
+	Total       int               `json:"total"`
+	Covered     int               `json:"covered"`
+	Coverage    float64           `json:"coverage"`
+	Sections    []SectionCoverage `json:"sections"`
+	Orphaned    []AuditCase       `json:"orphaned"`
+	Duplicates  []ClaimedCase     `json:"duplicates"`
+	// Unknown are cases claimed by tests which are not in suite
+	Unknown []ClaimedCase `json:"unknown"`
+}
+
+// SectionCoverage is number of section cases claimed by tests, subsections aren't counted
+type SectionCoverage struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Total    int     `json:"total"`
+	Covered  int     `json:"covered"`
+	Coverage float64 `json:"coverage"`
+}
+
+// AuditCase is suite case no test claims
+type AuditCase struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Section string `json:"section,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// ClaimedCase is case with tests claiming it
+type ClaimedCase struct {
+	ID    int      `json:"id"`
+	URL   string   `json:"url,omitempty"`
+	Tests []string `json:"tests"`
+}
+
+func coverage(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	// percents rounded to two digits keep json stable
+	return float64(covered*10000/total) / 100
+}
+
+// NewAudit compares suite cases with tests, sections are section names by id,
+// section id is used as name if it is unknown
+func NewAudit(cases types.TestCasesWithDescription, sections map[int]string, tests []*types.TestMatcher, f URLFormatter, now time.Time) Audit {
+	claimed := make(map[int][]string)
+	for _, t := range tests {
+		if t.ID == 0 {
+			continue
+		}
+		name := t.GoTestName
+		if t.Package != "" {
+			name = t.Package + "." + name
+		}
+		if !contains(claimed[t.ID], name) {
+			claimed[t.ID] = append(claimed[t.ID], name)
+		}
+	}
+
+	sectionName := func(id int) string {
+		if name, ok := sections[id]; ok {
+			return name
+		}
+		return fmt.Sprintf("section %d", id)
+	}
+
+	a := Audit{
+		GeneratedAt: now.UTC(),
+		Sections:    []SectionCoverage{},
+		Orphaned:    []AuditCase{},
+		Duplicates:  []ClaimedCase{},
+		Unknown:     []ClaimedCase{},
+	}
+	inSuite := make(map[int]bool, len(cases))
+	bySection := make(map[int]*SectionCoverage)
+	for _, c := range cases {
+		if inSuite[c.ID] {
+			continue
+		}
+		inSuite[c.ID] = true
+
+		s, ok := bySection[c.SectionID]
+		if !ok {
+			s = &SectionCoverage{ID: c.SectionID, Name: sectionName(c.SectionID)}
+			bySection[c.SectionID] = s
+		}
+		s.Total++
+		a.Total++
+
+		if _, ok := claimed[c.ID]; ok {
+			s.Covered++
+			a.Covered++
+			continue
+		}
+		a.Orphaned = append(a.Orphaned, AuditCase{
+			ID:      c.ID,
+			Title:   c.Description,
+			Section: s.Name,
+			URL:     f.FormatURL(c.ID),
+		})
+	}
+	a.Coverage = coverage(a.Covered, a.Total)
+
+	for _, s := range bySection {
+		s.Coverage = coverage(s.Covered, s.Total)
+		a.Sections = append(a.Sections, *s)
+	}
+	sort.Slice(a.Sections, func(i, j int) bool {
+		if a.Sections[i].Name != a.Sections[j].Name {
+			return a.Sections[i].Name < a.Sections[j].Name
+		}
+		return a.Sections[i].ID < a.Sections[j].ID
+	})
+	sort.Slice(a.Orphaned, func(i, j int) bool {
+		return a.Orphaned[i].ID < a.Orphaned[j].ID
+	})
+
+	for id, names := range claimed {
+		sort.Strings(names)
+		c := ClaimedCase{ID: id, Tests: names}
+		switch {
+		case !inSuite[id]:
+			a.Unknown = append(a.Unknown, c)
+		case len(names) > 1:
+			c.URL = f.FormatURL(id)
+			a.Duplicates = append(a.Duplicates, c)
+		}
+	}
+	for _, list := range [][]ClaimedCase{a.Duplicates, a.Unknown} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].ID < list[j].ID
+		})
+	}
+	return a
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes audit in json or markdown format
+func (a Audit) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(a)
+	case "markdown":
+		_, err := io.WriteString(w, a.markdown())
+		return err
+	default:
+		return fmt.Errorf("unsupported audit format %s", format)
+	}
+}
+
+func (a Audit) markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## TestRail audit\n\n%d of %d cases are covered by tests (%.2f%%)\n\n", a.Covered, a.Total, a.Coverage)
+	b.WriteString("| Section | Covered | Total | Coverage |\n| --- | --- | --- | --- |\n")
+	for _, s := range a.Sections {
+		fmt.Fprintf(&b, "| %s | %d | %d | %.2f%% |\n", markdownEscape(s.Name), s.Covered, s.Total, s.Coverage)
+	}
+
+	if len(a.Orphaned) > 0 {
+		b.WriteString("\n### Cases without tests\n\n| Case | Title | Section |\n| --- | --- | --- |\n")
+		for _, c := range a.Orphaned {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCase(ReportEntry{CaseID: c.ID, CaseURL: c.URL}),
+				markdownEscape(c.Title), markdownEscape(c.Section))
+		}
+	}
+	for _, group := range []struct {
+		title string
+		cases []ClaimedCase
+	}{
+		{"Cases claimed by several tests", a.Duplicates},
+		{"Cases not found in suite", a.Unknown},
+	} {
+		if len(group.cases) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n| Case | Tests |\n| --- | --- |\n", group.title)
+		for _, c := range group.cases {
+			tests := make([]string, 0, len(c.Tests))
+			for _, t := range c.Tests {
+				tests = append(tests, "`"+markdownEscape(t)+"`")
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", markdownCase(ReportEntry{CaseID: c.ID, CaseURL: c.URL}), strings.Join(tests, ", "))
+		}
+	}
+	return b.String()
+}
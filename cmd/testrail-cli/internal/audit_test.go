@@ -0,0 +1,80 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+func TestNewAudit(t *testing.T) {
+	cases := types.TestCasesWithDescription{
+		{ID: 1, Description: "first", SectionID: 10},
+		{ID: 2, Description: "second", SectionID: 10},
+		{ID: 3, Description: "third", SectionID: 11},
+		{ID: 4, Description: "fourth", SectionID: 12},
+	}
+	sections := map[int]string{10: "client", 11: "client / upload"}
+	tests := []*types.TestMatcher{
+		{Package: "mod/client", GoTestName: "TestFirst", ID: 1},
+		{Package: "mod/client", GoTestName: "TestFirst", ID: 1},
+		{Package: "mod/upload", GoTestName: "TestUpload", ID: 3},
+		{Package: "mod/upload", GoTestName: "TestUpload/retry", ID: 3},
+		{Package: "mod/upload", GoTestName: "TestRemoved", ID: 9},
+		{Package: "mod/upload", GoTestName: "TestNoCase"},
+	}
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	a := NewAudit(cases, sections, tests, testURLFormatter{}, now)
+	assert.Equal(t, Audit{
+		GeneratedAt: now,
+		Total:       4,
+		Covered:     2,
+		Coverage:    50,
+		Sections: []SectionCoverage{
+			{ID: 10, Name: "client", Total: 2, Covered: 1, Coverage: 50},
+			{ID: 11, Name: "client / upload", Total: 1, Covered: 1, Coverage: 100},
+			{ID: 12, Name: "section 12", Total: 1, Covered: 0, Coverage: 0},
+		},
+		Orphaned: []AuditCase{
+			{ID: 2, Title: "second", Section: "client", URL: "https://testrail/index.php?/cases/view/2"},
+			{ID: 4, Title: "fourth", Section: "section 12", URL: "https://testrail/index.php?/cases/view/4"},
+		},
+		Duplicates: []ClaimedCase{
+			{ID: 3, URL: "https://testrail/index.php?/cases/view/3", Tests: []string{"mod/upload.TestUpload", "mod/upload.TestUpload/retry"}},
+		},
+		Unknown: []ClaimedCase{
+			{ID: 9, Tests: []string{"mod/upload.TestRemoved"}},
+		},
+	}, a)
+}
+
+func TestAudit_Write(t *testing.T) {
+	a := NewAudit(types.TestCasesWithDescription{{ID: 1, Description: "first", SectionID: 10}},
+		nil, nil, testURLFormatter{}, time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC))
+
+	var b strings.Builder
+	require.NoError(t, a.Write(&b, "json"))
+	var decoded Audit
+	require.NoError(t, json.Unmarshal([]byte(b.String()), &decoded))
+	assert.Equal(t, a, decoded)
+	// empty lists are kept in json, so history consumers don't check for null
+	assert.Contains(t, b.String(), `"duplicates": []`)
+
+	b.Reset()
+	require.NoError(t, a.Write(&b, "markdown"))
+	assert.Contains(t, b.String(), "0 of 1 cases are covered by tests (0.00%)")
+	assert.Contains(t, b.String(), "| [C1](https://testrail/index.php?/cases/view/1) | first | section 10 |")
+
+	assert.Error(t, a.Write(&b, "junit"))
+}
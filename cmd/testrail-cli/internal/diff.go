@@ -0,0 +1,76 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// unifiedDiff returns diff of texts with the same number of lines, which is enough
+// for in-place rewrites, changed lines are grouped into hunks with context
+func unifiedDiff(from, to, a, b string) string {
+	var (
+		oldLines = strings.SplitAfter(a, "\n")
+		newLines = strings.SplitAfter(b, "\n")
+		changed  []int
+	)
+	if len(oldLines) != len(newLines) {
+		return ""
+	}
+	for i := range oldLines {
+		if oldLines[i] != newLines[i] {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+	for i := 0; i < len(changed); {
+		start := changed[i] - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// hunk is extended while next change is within context
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*diffContext {
+			j++
+		}
+		end := changed[j] + diffContext
+		if end > len(oldLines)-1 {
+			end = len(oldLines) - 1
+		}
+		// text ending with newline has empty last element
+		if oldLines[end] == "" {
+			end--
+		}
+
+		count := end - start + 1
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", start+1, count, start+1, count)
+		for k := start; k <= end; k++ {
+			if oldLines[k] == newLines[k] {
+				out.WriteString(" " + withNewline(oldLines[k]))
+				continue
+			}
+			out.WriteString("-" + withNewline(oldLines[k]))
+			out.WriteString("+" + withNewline(newLines[k]))
+		}
+		i = j + 1
+	}
+	return out.String()
+}
+
+func withNewline(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return line
+	}
+	return line + "\n\\ No newline at end of file\n"
+}
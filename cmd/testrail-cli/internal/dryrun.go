@@ -0,0 +1,37 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	trlib "github.com/educlos/testrail"
+)
+
+// PrintPayload writes results, which would be sent to testrail, in table or json format,
+// statusName names testrail status ids in table
+func PrintPayload(w io.Writer, runID int, payload trlib.SendableResultsForCase, format string, statusName func(int) string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(payload)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Run %d, %d results\n", runID, len(payload.Results))
+		fmt.Fprintln(tw, "CASE\tSTATUS\tELAPSED\tDEFECTS\tASSIGNEE\tVERSION")
+		for _, r := range payload.Results {
+			fmt.Fprintf(tw, "C%d\t%s\t%s\t%s\t%d\t%s\n",
+				r.CaseID, statusName(r.StatusID), r.Elapsed.Duration, r.Defects, r.AssignedToID, r.Version)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported dry run output %s", format)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	trlib "github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPayload() trlib.SendableResultsForCase {
+	return trlib.SendableResultsForCase{Results: []trlib.ResultsForCase{
+		{CaseID: 1, SendableResult: trlib.SendableResult{
+			StatusID: 1, Elapsed: *trlib.TimespanFromDuration(2 * time.Second), AssignedToID: 10, Version: "1",
+		}},
+		{CaseID: 22, SendableResult: trlib.SendableResult{
+			StatusID: 6, Elapsed: *trlib.TimespanFromDuration(time.Second), Defects: "PLAT-1", AssignedToID: 10, Version: "1",
+		}},
+	}}
+}
+
+func statusName(id int) string {
+	return map[int]string{1: "PASS", 6: "SKIP"}[id]
+}
+
+func TestPrintPayload(t *testing.T) {
+	for _, format := range []string{"table", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, PrintPayload(&buf, 57, testPayload(), format, statusName))
+
+			golden, err := ioutil.ReadFile(filepath.Join("testdata", "dryrun", "payload."+format))
+			require.NoError(t, err)
+			assert.Equal(t, string(golden), buf.String())
+		})
+	}
+
+	err := PrintPayload(&bytes.Buffer{}, 57, testPayload(), "yaml", strconv.Itoa)
+	assert.EqualError(t, err, "unsupported dry run output yaml")
+}
@@ -7,7 +7,9 @@ package internal
 
 import (
 	"log"
+	"sort"
 
+	"github.com/insolar/testrail-cli/parser"
 	"github.com/insolar/testrail-cli/types"
 )
 
@@ -17,6 +19,7 @@ func FilterTestObjects(objectList []*types.TestMatcher, caseList types.TestCases
 		summary   TestObjectSummary
 		objectMap = make(map[int]*types.TestMatcher)
 		caseMap   = make(map[int]types.TestCaseWithDescription)
+		ambiguous = make(map[string]bool)
 	)
 
 	for _, c := range caseList {
@@ -27,6 +30,11 @@ func FilterTestObjects(objectList []*types.TestMatcher, caseList types.TestCases
 		if object.Status == "SKIP" && object.IssueURL == "" {
 			summary.SkippedNoIssue = append(summary.SkippedNoIssue, object)
 		}
+		// test with several cases is split into objects sharing ambiguous lines
+		if key := parser.UniqueTestKeyFromFields(object.Package, object.GoTestName); len(object.Ambiguous) > 0 && !ambiguous[key] {
+			ambiguous[key] = true
+			summary.Ambiguous = append(summary.Ambiguous, object)
+		}
 
 		if object.ID != 0 {
 			if _, ok := objectMap[object.ID]; ok {
@@ -52,3 +60,20 @@ func FilterTestObjects(objectList []*types.TestMatcher, caseList types.TestCases
 
 	return &summary
 }
+
+// CaseIDs returns sorted unique case IDs found in test objects
+func CaseIDs(objects []*types.TestMatcher) []int {
+	var (
+		ids  []int
+		seen = make(map[int]bool)
+	)
+	for _, object := range objects {
+		if object.ID == 0 || seen[object.ID] {
+			continue
+		}
+		seen[object.ID] = true
+		ids = append(ids, object.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
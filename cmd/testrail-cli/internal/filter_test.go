@@ -0,0 +1,35 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+func TestCaseIDs(t *testing.T) {
+	assert.Equal(t, []int{1, 3, 7}, CaseIDs([]*types.TestMatcher{
+		{ID: 7}, {ID: 1}, {ID: 0}, {ID: 3}, {ID: 7, GoTestName: "TestOther"},
+	}))
+	assert.Empty(t, CaseIDs([]*types.TestMatcher{{GoTestName: "TestNoCase"}}))
+}
+
+func TestFilterTestObjects_Ambiguous(t *testing.T) {
+	line := []string{"C1 first C2 second"}
+	summary := FilterTestObjects([]*types.TestMatcher{
+		{ID: 1, Package: "parser/json", GoTestName: "TestParse", Ambiguous: line},
+		{ID: 2, Package: "parser/json", GoTestName: "TestParse", Ambiguous: line},
+		{ID: 3, Package: "parser/text", GoTestName: "TestParse", Ambiguous: line},
+	}, nil, true)
+
+	require.Len(t, summary.Ambiguous, 2)
+	assert.Equal(t, "parser/json", summary.Ambiguous[0].Package)
+	assert.Equal(t, "parser/text", summary.Ambiguous[1].Package)
+}
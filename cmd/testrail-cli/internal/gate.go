@@ -0,0 +1,113 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+// Quality gates, ordered by priority of their exit codes
+const (
+	GateNotFound    = "not-found"
+	GateWrongDesc   = "wrong-desc"
+	GateSkipNoIssue = "skip-no-issue"
+	GateFailedTests = "failed-tests"
+)
+
+// GateNames lists supported gates in order of priority
+var GateNames = []string{GateNotFound, GateWrongDesc, GateSkipNoIssue, GateFailedTests}
+
+// gateExitCodes are distinct from 1 used for errors and 2 used for invalid flags
+var gateExitCodes = map[string]int{
+	GateNotFound:    3,
+	GateWrongDesc:   4,
+	GateSkipNoIssue: 5,
+	GateFailedTests: 6,
+}
+
+// Gate fails run when number of tests in its group exceeds Max
+type Gate struct {
+	Name string
+	Max  int
+}
+
+// GateViolation is gate with number of tests over the limit
+type GateViolation struct {
+	Gate
+	Count int
+}
+
+func (v GateViolation) Error() string {
+	return fmt.Sprintf("quality gate %s failed: %d tests, %d allowed", v.Name, v.Count, v.Max)
+}
+
+// ExitCode returns process exit code of failed gate
+func (v GateViolation) ExitCode() int {
+	return gateExitCodes[v.Name]
+}
+
+// ParseGates builds gates from comma separated list of names and limits, gate with limit is
+// enabled even if it isn't listed, limit of listed gate without one is zero, negative limit is unset
+func ParseGates(failOn string, limits map[string]int) ([]Gate, error) {
+	enabled := make(map[string]bool)
+	// gates listed in config file are space separated
+	for _, name := range strings.Fields(strings.Replace(failOn, ",", " ", -1)) {
+		if _, ok := gateExitCodes[name]; !ok {
+			return nil, fmt.Errorf("unsupported quality gate %s, use one of %s", name, strings.Join(GateNames, ","))
+		}
+		enabled[name] = true
+	}
+
+	var gates []Gate
+	for _, name := range GateNames {
+		limit, ok := limits[name]
+		if !ok || limit < 0 {
+			if !enabled[name] {
+				continue
+			}
+			limit = 0
+		}
+		gates = append(gates, Gate{Name: name, Max: limit})
+	}
+	return gates, nil
+}
+
+// count returns number of tests checked by gate
+func (s TestObjectSummary) count(gate string) int {
+	switch gate {
+	case GateNotFound:
+		return len(s.NotFound)
+	case GateWrongDesc:
+		return len(s.WrongDesc)
+	case GateSkipNoIssue:
+		return len(s.SkippedNoIssue)
+	case GateFailedTests:
+		failed := 0
+		for _, group := range [][]*types.TestMatcher{s.Valid, s.NotFound, s.WrongDesc} {
+			for _, o := range group {
+				if o.Status == types.TestStatusFailed {
+					failed++
+				}
+			}
+		}
+		return failed
+	}
+	return 0
+}
+
+// CheckGates returns violated gates in order of priority
+func (s TestObjectSummary) CheckGates(gates []Gate) []GateViolation {
+	var violations []GateViolation
+	for _, gate := range gates {
+		if count := s.count(gate.Name); count > gate.Max {
+			violations = append(violations, GateViolation{Gate: gate, Count: count})
+		}
+	}
+	return violations
+}
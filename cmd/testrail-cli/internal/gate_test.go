@@ -0,0 +1,50 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGates(t *testing.T) {
+	gates, err := ParseGates("failed-tests, not-found", map[string]int{
+		GateNotFound:    5,
+		GateWrongDesc:   -1,
+		GateSkipNoIssue: 2,
+		GateFailedTests: -1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Gate{
+		{Name: GateNotFound, Max: 5},
+		{Name: GateSkipNoIssue, Max: 2},
+		{Name: GateFailedTests, Max: 0},
+	}, gates)
+
+	gates, err = ParseGates("failed-tests not-found", nil)
+	require.NoError(t, err)
+	assert.Len(t, gates, 2)
+
+	_, err = ParseGates("not-found,typo", nil)
+	assert.Error(t, err)
+}
+
+func TestTestObjectSummary_CheckGates(t *testing.T) {
+	summary := testSummary()
+	violations := summary.CheckGates([]Gate{
+		{Name: GateNotFound, Max: 1},
+		{Name: GateWrongDesc, Max: 0},
+		{Name: GateFailedTests, Max: 0},
+	})
+
+	require.Len(t, violations, 2)
+	assert.Equal(t, GateWrongDesc, violations[0].Name)
+	assert.Equal(t, 4, violations[0].ExitCode())
+	assert.Equal(t, GateFailedTests, violations[1].Name)
+	assert.Equal(t, 1, violations[1].Count)
+}
@@ -3,7 +3,9 @@
 //  This material is licensed under the Insolar License version 1.0,
 //  available at https://github.com/insolar/testrail-cli/LICENSE.md.
 
-package internal
+// Package logfmt converts assured-ledger test logs, case, status and skip link are logged
+// as logfmt fields of "testrail" line: testrail ID=C5005 Status=PASS TestName=... TestPackage=...
+package logfmt
 
 import (
 	"log"
@@ -12,17 +14,17 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/insolar/testrail-cli/issue"
 	"github.com/insolar/testrail-cli/parser"
 	"github.com/insolar/testrail-cli/types"
 )
 
 var (
-	testCaseIDRe    = regexp.MustCompile(`C(\d{1,8})`)
-	testSkipIssueRe = regexp.MustCompile(`insolar\.atlassian\.net/browse/([A-Z]+-\d+)`)
-
+	testCaseIDRe = regexp.MustCompile(`C(\d{1,8})`)
 )
 
 type logLineParserState int
+
 const (
 	StateMessage logLineParserState = iota
 	StateKey
@@ -94,7 +96,7 @@ func Reverse(line string) string {
 	)
 
 	for _, r := range line {
-		invertedLine[cap(invertedLine)-1 - pos] = r
+		invertedLine[cap(invertedLine)-1-pos] = r
 		pos += 1
 	}
 
@@ -151,15 +153,22 @@ func logLineParseAlternative(line string) (string, map[string]string, error) {
 	}
 }
 
-type Converter struct {}
+type Converter struct {
+	// Issues extracts defects from skipped test links, issue.Default is used if empty
+	Issues issue.Matcher
+}
 
 func (c Converter) ConvertEventsToMatcherObjectsPreload(events map[string][]parser.TestEvent) []*types.TestMatcher {
 	reader := parser.NewStreamingEventReaderFromMap(events)
 	return c.ConvertEventsToMatcherObjects(reader)
 }
 
-func (Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*types.TestMatcher {
+func (c Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*types.TestMatcher {
 	matchers := make(map[string]*types.TestMatcher)
+	issues := c.Issues
+	if len(issues) == 0 {
+		issues = issue.Default
+	}
 
 	for {
 		_, event, err := reader.Next()
@@ -169,7 +178,6 @@ func (Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*typ
 			log.Fatal(err)
 		}
 
-
 		if event.Action == "output" {
 			if !strings.Contains(event.Output, "testrail ") {
 				continue
@@ -212,9 +220,8 @@ func (Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*typ
 
 			issueURL, ok := lineFields["SkippedLink"]
 			if ok {
-				submatches := testSkipIssueRe.FindStringSubmatch(issueURL)
-				if len(submatches) == 2 {
-					t.IssueURL = submatches[1]
+				if defect, ok := issues.Find(issueURL); ok {
+					t.IssueURL = defect
 				}
 			}
 
@@ -226,11 +233,12 @@ func (Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*typ
 	for _, val := range matchers {
 		if val.ID == 0 {
 			continue
-		} else if val.Status == "" {
-			val.Status = "FAIL"
+		} else if !types.StatusKnown(val.Status) {
+			// test which didn't log its status, ex.: panicked, is failed
+			val.Status = types.TestStatusFailed
 		}
 		matcherList = append(matcherList, val)
 	}
 
 	return matcherList
-}
\ No newline at end of file
+}
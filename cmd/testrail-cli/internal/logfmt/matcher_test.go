@@ -3,23 +3,28 @@
 //  This material is licensed under the Insolar License version 1.0,
 //  available at https://github.com/insolar/testrail-cli/LICENSE.md.
 
-package internal
+package logfmt
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/parser"
+	"github.com/insolar/testrail-cli/types"
 )
 
 func Test_logLineParse(t *testing.T) {
 	line := `testrail caller=testutils/investigation/testrail.go:126 ID=C5005 TestName=TestConstructor_SamePulse_AfterExecution TestPackage=github.com/insolar/assured-ledger/ledger-core/virtual/integration/deduplication testname=TestConstructor_SamePulse_WhileExecution`
 
 	expectedFields := map[string]string{
-		"caller":"testutils/investigation/testrail.go:126",
-		"ID":"C5005",
-		"TestName": "TestConstructor_SamePulse_AfterExecution",
+		"caller":      "testutils/investigation/testrail.go:126",
+		"ID":          "C5005",
+		"TestName":    "TestConstructor_SamePulse_AfterExecution",
 		"TestPackage": "github.com/insolar/assured-ledger/ledger-core/virtual/integration/deduplication",
-		"testname": "TestConstructor_SamePulse_WhileExecution",
+		"testname":    "TestConstructor_SamePulse_WhileExecution",
 	}
 
 	t.Run("test basic", func(t *testing.T) {
@@ -31,14 +36,14 @@ func Test_logLineParse(t *testing.T) {
 	})
 }
 
-func Test_logLineParseAlternative (t *testing.T) {
+func Test_logLineParseAlternative(t *testing.T) {
 	line1 := `testrail caller=testutils/investigation/testrail.go:126 ID=C5005 TestName=TestConstructor_SamePulse_AfterExecution TestPackage=github.com/insolar/assured-ledger/ledger-core/virtual/integration/deduplication testname=TestConstructor_SamePulse_WhileExecution`
 	expectedFields1 := map[string]string{
-		"caller":"testutils/investigation/testrail.go:126",
-		"ID":"C5005",
-		"TestName": "TestConstructor_SamePulse_AfterExecution",
+		"caller":      "testutils/investigation/testrail.go:126",
+		"ID":          "C5005",
+		"TestName":    "TestConstructor_SamePulse_AfterExecution",
 		"TestPackage": "github.com/insolar/assured-ledger/ledger-core/virtual/integration/deduplication",
-		"testname": "TestConstructor_SamePulse_WhileExecution",
+		"testname":    "TestConstructor_SamePulse_WhileExecution",
 	}
 	expectedMessage1 := "testrail"
 
@@ -52,10 +57,10 @@ func Test_logLineParseAlternative (t *testing.T) {
 
 	line2 := `Got Bootstrap request from host id: 0 ref: insolar:1GZ2ZjnsgEsQp49Lz0inGkDKoY2RrJzF3XH_n7gAAAAY addr: 127.0.0.1:10006; RequestID = 2 caller=network/hostnetwork/hostnetwork.go:136 loginstance=node testname=TestNodeLeave traceid=`
 	expectedFields2 := map[string]string{
-		"caller":"network/hostnetwork/hostnetwork.go:136",
-		"loginstance":"node",
-		"testname":"TestNodeLeave",
-		"traceid":"",
+		"caller":      "network/hostnetwork/hostnetwork.go:136",
+		"loginstance": "node",
+		"testname":    "TestNodeLeave",
+		"traceid":     "",
 	}
 	expectedMessage2 := "Got Bootstrap request from host id: 0 ref: insolar:1GZ2ZjnsgEsQp49Lz0inGkDKoY2RrJzF3XH_n7gAAAAY addr: 127.0.0.1:10006; RequestID = 2"
 
@@ -69,10 +74,10 @@ func Test_logLineParseAlternative (t *testing.T) {
 
 	line3 := `=== AddJoinCandidate id = 2483507232, address = 127.0.0.1:10006  caller=network/gateway/base.go:349 loginstance=node testname=TestNodeLeave traceid=`
 	expectedFields3 := map[string]string{
-		"caller":"network/gateway/base.go:349",
-		"loginstance":"node",
-		"testname":"TestNodeLeave",
-		"traceid":"",
+		"caller":      "network/gateway/base.go:349",
+		"loginstance": "node",
+		"testname":    "TestNodeLeave",
+		"traceid":     "",
 	}
 	expectedMessage3 := "=== AddJoinCandidate id = 2483507232, address = 127.0.0.1:10006"
 
@@ -83,4 +88,26 @@ func Test_logLineParseAlternative (t *testing.T) {
 		assert.Equal(t, expectedFields3, fields)
 		assert.NoError(t, err)
 	})
-}
\ No newline at end of file
+}
+
+func TestConverter_UnknownStatus(t *testing.T) {
+	pkg := "example.com/ledger"
+	events := map[string][]parser.TestEvent{
+		parser.UniqueTestKeyFromFields(pkg, "TestBroken"): {
+			{Action: "output", Package: pkg, Test: "TestBroken", Output: "testrail ID=C1 Status=BROKEN TestName=TestBroken TestPackage=" + pkg},
+		},
+		parser.UniqueTestKeyFromFields(pkg, "TestSilent"): {
+			{Action: "output", Package: pkg, Test: "TestSilent", Output: "testrail ID=C2 TestName=TestSilent TestPackage=" + pkg},
+		},
+		parser.UniqueTestKeyFromFields(pkg, "TestSkipped"): {
+			{Action: "output", Package: pkg, Test: "TestSkipped", Output: "testrail ID=C3 Status=SKIP TestName=TestSkipped TestPackage=" + pkg},
+		},
+	}
+
+	matchers := Converter{}.ConvertEventsToMatcherObjectsPreload(events)
+	require.Len(t, matchers, 3)
+	sort.Slice(matchers, func(i, j int) bool { return matchers[i].ID < matchers[j].ID })
+	assert.Equal(t, types.TestStatusFailed, matchers[0].Status)
+	assert.Equal(t, types.TestStatusFailed, matchers[1].Status)
+	assert.Equal(t, types.TestStatusSkipped, matchers[2].Status)
+}
@@ -3,7 +3,7 @@
 //  This material is licensed under the Insolar License version 1.0,
 //  available at https://github.com/insolar/testrail-cli/LICENSE.md.
 
-package internal
+package logfmt
 
 import (
 	"errors"
@@ -35,7 +35,7 @@ func parseMessage(leftovers string, skipEqualSign int) (string, []byte, error) {
 			}
 			return leftovers[lastWordPos-1:], []byte(leftovers[:lastWordPos-1]), nil
 		case unicode.IsSpace(r):
-			lastWordPos = i+1
+			lastWordPos = i + 1
 		}
 	}
 
@@ -58,7 +58,6 @@ func parseKeyString(leftovers string) (string, []byte, error) {
 	return leftovers, nil, errors.New("malformed key 2")
 }
 
-
 func parseReverseKeyString(leftovers string) (string, []byte, error) {
 	for i, r := range leftovers {
 		switch {
@@ -90,7 +89,7 @@ func parseJsonValueString(leftovers string) (string, []byte, error) {
 				return leftovers, nil, errors.New("malformed string 1")
 			}
 
-			newPosition := i+2
+			newPosition := i + 2
 			switch leftovers[i+1] {
 			case '"':
 				result = append(result, '"')
@@ -118,15 +117,15 @@ func parseJsonValueString(leftovers string) (string, []byte, error) {
 
 				var b []byte
 				for unquotedNumber > 0 {
-					b = append(b, byte(unquotedNumber) & 255)
+					b = append(b, byte(unquotedNumber)&255)
 					unquotedNumber >>= 8
 				}
 
 				for i := 0; i < len(b); i++ {
-					result = append(result, b[len(b) - (i+1)])
+					result = append(result, b[len(b)-(i+1)])
 				}
 
-				newPosition = i+6
+				newPosition = i + 6
 			default:
 				return leftovers, nil, errors.New("malformed string 4")
 			}
@@ -166,7 +165,7 @@ func parseReverseJsonValueString(leftovers string) (string, []byte, error) {
 				return leftovers, nil, errors.New("malformed string 1")
 			}
 
-			newPosition := i+2
+			newPosition := i + 2
 			switch leftovers[i+1] {
 			case '"':
 				result = append(result, '"')
@@ -194,15 +193,15 @@ func parseReverseJsonValueString(leftovers string) (string, []byte, error) {
 
 				var b []byte
 				for unquotedNumber > 0 {
-					b = append(b, byte(unquotedNumber) & 255)
+					b = append(b, byte(unquotedNumber)&255)
 					unquotedNumber >>= 8
 				}
 
 				for i := 0; i < len(b); i++ {
-					result = append(result, b[len(b) - (i+1)])
+					result = append(result, b[len(b)-(i+1)])
 				}
 
-				newPosition = i+6
+				newPosition = i + 6
 			default:
 				return leftovers, nil, errors.New("malformed string 4")
 			}
@@ -3,7 +3,7 @@
 //  This material is licensed under the Insolar License version 1.0,
 //  available at https://github.com/insolar/testrail-cli/LICENSE.md.
 
-package internal
+package logfmt
 
 import (
 	"testing"
@@ -232,4 +232,4 @@ func Test_parseJsonValueString(t *testing.T) {
 		assert.Equal(t, "+", string(obj))
 		assert.NoError(t, err)
 	})
-}
\ No newline at end of file
+}
@@ -8,74 +8,200 @@ package internal
 import (
 	"log"
 	"regexp"
-	"strconv"
+	"strings"
 
+	"github.com/insolar/testrail-cli/issue"
+	"github.com/insolar/testrail-cli/marker"
 	"github.com/insolar/testrail-cli/parser"
+	"github.com/insolar/testrail-cli/testrail"
 	"github.com/insolar/testrail-cli/types"
 )
 
 var (
-	testStatusRe    = regexp.MustCompile(`--- (.*):`)
-	testSkipIssueRe = regexp.MustCompile(`insolar\.atlassian\.net/browse/([A-Z]+-\d+)`)
-	testCaseIdRe    = regexp.MustCompile(`C(\d{1,8})\s(.*)`)
+	// testLogRe matches name of test which called t.Log, ex.: "    TestExample: example_test.go:16: ",
+	// output of parallel tests is often attributed to another test, so the name is trusted more
+	testLogRe = regexp.MustCompile(`^\s*(\S+): \S+\.go:\d+: `)
+	// statusLineRe matches test status line, ex.: "--- PASS: TestExample (0.31s)"
+	statusLineRe = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): `)
+
+	actionStatus = map[string]string{
+		"pass": types.TestStatusPassed,
+		"fail": types.TestStatusFailed,
+		"skip": types.TestStatusSkipped,
+	}
 )
 
-type Converter struct {}
+type Converter struct {
+	// MaxOutputSize limits captured test output, which is uploaded as result comment
+	MaxOutputSize int
+	// Issues extracts defects from output of skipped tests, issue.Default is used if empty
+	Issues issue.Matcher
+	// Markers find case references in test output, marker.Default is used if empty
+	Markers marker.Set
+	// Mapping is cases found by scan command, they are used for tests which logged no case,
+	// ex.: test panicked before t.Log
+	Mapping map[string][]types.CaseRef
+}
+
+// testNode is test in tree built from test events, subtest parent is the test
+// named by subtest name up to the last slash
+type testNode struct {
+	pkg     string
+	test    string
+	matcher *types.TestMatcher
+	output  *testrail.OutputBuffer
+}
+
+type testTree struct {
+	nodes map[string]*testNode
+	order []*testNode
+	size  int
+}
+
+func (tr *testTree) get(pkg, test string) *testNode {
+	key := parser.UniqueTestKeyFromFields(pkg, test)
+	node, ok := tr.nodes[key]
+	if !ok {
+		node = &testNode{
+			pkg:     pkg,
+			test:    test,
+			matcher: &types.TestMatcher{GoTestName: test, Package: pkg},
+			output:  testrail.NewOutputBuffer(tr.size),
+		}
+		tr.nodes[key] = node
+		tr.order = append(tr.order, node)
+	}
+	return node
+}
+
+func (tr *testTree) lookup(pkg, test string) (*testNode, bool) {
+	node, ok := tr.nodes[parser.UniqueTestKeyFromFields(pkg, test)]
+	return node, ok
+}
+
+func (tr *testTree) parent(node *testNode) *testNode {
+	for test := node.test; ; {
+		i := strings.LastIndex(test, "/")
+		if i < 0 {
+			return nil
+		}
+		test = test[:i]
+		if parent, ok := tr.lookup(node.pkg, test); ok {
+			return parent
+		}
+	}
+}
+
+// owner returns test which logged output line, event test is used if line has no test name
+func (tr *testTree) owner(node *testNode, output string) *testNode {
+	res := testLogRe.FindStringSubmatch(output)
+	if len(res) != 2 || res[1] == node.test {
+		return node
+	}
+	if owner, ok := tr.lookup(node.pkg, res[1]); ok {
+		return owner
+	}
+	return node
+}
 
 func (c Converter) ConvertEventsToMatcherObjectsPreload(events map[string][]parser.TestEvent) []*types.TestMatcher {
 	reader := parser.NewStreamingEventReaderFromMap(events)
 	return c.ConvertEventsToMatcherObjects(reader)
 }
 
-func (Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*types.TestMatcher {
-	matchers := make(map[string]*types.TestMatcher)
+func (c Converter) ConvertEventsToMatcherObjects(reader parser.EventReader) []*types.TestMatcher {
+	tree := &testTree{nodes: make(map[string]*testNode), size: c.MaxOutputSize}
+	issues := c.Issues
+	if len(issues) == 0 {
+		issues = issue.Default
+	}
+	markers := c.Markers
+	if len(markers) == 0 {
+		markers = marker.Default
+	}
 
 	for {
-		name, event, err := reader.Next()
+		_, event, err := reader.Next()
 		if parser.IsEOF(err) {
 			break
 		} else if err != nil {
 			log.Fatal(err)
 		}
 
-
-		if event.Action == "output" {
-			if event.Test == "" {
+		if event.Test == "" {
+			continue
+		}
+		node := tree.get(event.Package, event.Test)
+
+		switch event.Action {
+		case "pass", "fail", "skip":
+			node.matcher.Status = actionStatus[event.Action]
+			node.matcher.Elapsed = event.Elapsed
+		case "output":
+			owner := tree.owner(node, event.Output)
+			owner.output.Add(event.Output)
+			// status line repeats test name matched on run line already, ex.: junit test named after case,
+			// with elapsed time appended to title
+			if statusLineRe.MatchString(event.Output) {
 				continue
 			}
 
-			t, ok := matchers[name]
-			if !ok {
-				t = &types.TestMatcher{}
-				matchers[name] = t
-			}
-
-			t.GoTestName = event.Test
-
-			if res := testCaseIdRe.FindStringSubmatch(event.Output); len(res) == 3 {
-				d, err := strconv.Atoi(res[1])
-				if err != nil {
-					log.Fatal(err)
-				}
-				// TODO: Bad, should harden regex instead, debatable for now
-				if t.ID != 0 {
-					continue
-				}
-				t.ID = d
-				t.Description = res[2]
-			} else if res := testStatusRe.FindStringSubmatch(event.Output); len(res) == 2 {
-				t.Status = res[1]
-			} else if res := testSkipIssueRe.FindStringSubmatch(event.Output); len(res) == 2 {
-				t.IssueURL = res[1]
+			if matches := markers.Find(event.Output); len(matches) == 1 {
+				addCase(owner.matcher, matches[0].Ref, event.Output)
+			} else if len(matches) > 1 {
+				// the first one isn't necessarily the right one
+				addAmbiguous(owner.matcher, event.Output)
+			} else if defect, ok := issues.Find(event.Output); ok {
+				owner.matcher.IssueURL = defect
 			}
 		}
 	}
 
+	// tests with subtests logging cases just group them, they aren't reported
+	grouping := make(map[*testNode]bool)
+	for _, node := range tree.order {
+		if len(node.matcher.Cases) == 0 {
+			continue
+		}
+		for parent := tree.parent(node); parent != nil; parent = tree.parent(parent) {
+			grouping[parent] = true
+		}
+	}
 
-	matcherList := make([]*types.TestMatcher, 0, len(matchers))
-	for _, val := range matchers {
-		matcherList = append(matcherList, val)
+	matcherList := make([]*types.TestMatcher, 0, len(tree.order))
+	for _, node := range tree.order {
+		if grouping[node] && len(node.matcher.Cases) == 0 {
+			continue
+		}
+		if len(node.matcher.Cases) == 0 {
+			key := parser.UniqueTestKeyFromFields(node.pkg, node.test)
+			node.matcher.Cases = append(node.matcher.Cases, c.Mapping[key]...)
+		}
+		// subtest without result, ex.: test binary panicked, gets status of the nearest ancestor
+		for parent := tree.parent(node); node.matcher.Status == "" && parent != nil; parent = tree.parent(parent) {
+			node.matcher.Status = parent.matcher.Status
+		}
+		node.matcher.Output = node.output.String()
+		matcherList = append(matcherList, node.matcher.SplitCases()...)
 	}
 
 	return matcherList
-}
\ No newline at end of file
+}
+
+// addCase adds case to test unless it is already logged, ex.: by test run with -count,
+// case logged again with another title is ambiguous
+func addCase(t *types.TestMatcher, ref types.CaseRef, line string) {
+	for _, c := range t.Cases {
+		if c.ID == ref.ID {
+			if c.Description != ref.Description {
+				addAmbiguous(t, line)
+			}
+			return
+		}
+	}
+	t.Cases = append(t.Cases, ref)
+}
+
+func addAmbiguous(t *types.TestMatcher, line string) {
+	t.Ambiguous = append(t.Ambiguous, strings.TrimSpace(line))
+}
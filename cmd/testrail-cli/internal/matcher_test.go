@@ -0,0 +1,133 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/marker"
+	"github.com/insolar/testrail-cli/parser/json"
+	"github.com/insolar/testrail-cli/parser/junit"
+	"github.com/insolar/testrail-cli/types"
+)
+
+func convert(t *testing.T, c Converter, log string) []*types.TestMatcher {
+	reader := json.Parser{}.GetParseIterator(strings.NewReader(log))
+	objects := c.ConvertEventsToMatcherObjects(reader)
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
+	require.NotEmpty(t, objects)
+	return objects
+}
+
+func TestConverter_MultipleCases(t *testing.T) {
+	objects := convert(t, Converter{}, `{"Action":"run","Package":"pkg","Test":"TestTable"}
+{"Action":"output","Package":"pkg","Test":"TestTable","Output":"=== RUN   TestTable\n"}
+{"Action":"output","Package":"pkg","Test":"TestTable","Output":"    table_test.go:10: C101 First case\n"}
+{"Action":"output","Package":"pkg","Test":"TestTable","Output":"    table_test.go:10: C102 Second case\n"}
+{"Action":"output","Package":"pkg","Test":"TestTable","Output":"    table_test.go:10: C101 First case\n"}
+{"Action":"output","Package":"pkg","Test":"TestTable","Output":"    table_test.go:12: TC103 not a case\n"}
+{"Action":"output","Package":"pkg","Test":"TestTable","Output":"--- FAIL: TestTable (0.50s)\n"}
+{"Action":"fail","Package":"pkg","Test":"TestTable","Elapsed":0.5}
+`)
+
+	require.Len(t, objects, 2)
+	assert.Equal(t, 101, objects[0].ID)
+	assert.Equal(t, "First case", objects[0].Description)
+	assert.Equal(t, 102, objects[1].ID)
+	assert.Equal(t, "Second case", objects[1].Description)
+	for _, o := range objects {
+		assert.Equal(t, "FAIL", o.Status)
+		assert.Equal(t, "TestTable", o.GoTestName)
+		assert.Equal(t, []types.CaseRef{{ID: 101, Description: "First case"}, {ID: 102, Description: "Second case"}}, o.Cases)
+	}
+}
+
+func TestConverter_Subtests(t *testing.T) {
+	objects := convert(t, Converter{}, `{"Action":"run","Package":"pkg","Test":"TestGroup"}
+{"Action":"run","Package":"pkg","Test":"TestGroup/ok"}
+{"Action":"output","Package":"pkg","Test":"TestGroup/ok","Output":"    TestGroup/ok: group_test.go:10: C201 Passing subtest\n"}
+{"Action":"run","Package":"pkg","Test":"TestGroup/broken"}
+{"Action":"output","Package":"pkg","Test":"TestGroup/broken","Output":"    TestGroup/broken: group_test.go:10: C202 Failing subtest\n"}
+{"Action":"run","Package":"pkg","Test":"TestGroup/broken/nested"}
+{"Action":"output","Package":"pkg","Test":"TestGroup/broken/nested","Output":"    TestGroup/broken/nested: group_test.go:10: C203 Subtest without result\n"}
+{"Action":"run","Package":"pkg","Test":"TestParallel"}
+{"Action":"output","Package":"pkg","Test":"TestGroup/ok","Output":"    TestParallel: parallel_test.go:5: C204 Misattributed log\n"}
+{"Action":"output","Package":"pkg","Test":"TestGroup","Output":"--- FAIL: TestGroup (0.30s)\n"}
+{"Action":"output","Package":"pkg","Test":"TestGroup/ok","Output":"    --- PASS: TestGroup/ok (0.10s)\n"}
+{"Action":"pass","Package":"pkg","Test":"TestGroup/ok","Elapsed":0.1}
+{"Action":"output","Package":"pkg","Test":"TestGroup/broken","Output":"    --- FAIL: TestGroup/broken (0.20s)\n"}
+{"Action":"fail","Package":"pkg","Test":"TestGroup/broken","Elapsed":0.2}
+{"Action":"fail","Package":"pkg","Test":"TestGroup","Elapsed":0.3}
+{"Action":"skip","Package":"pkg","Test":"TestParallel","Elapsed":0.4}
+`)
+
+	require.Len(t, objects, 4)
+	expected := []struct {
+		id     int
+		status string
+		test   string
+	}{
+		{201, "PASS", "TestGroup/ok"},
+		{202, "FAIL", "TestGroup/broken"},
+		{203, "FAIL", "TestGroup/broken/nested"},
+		{204, "SKIP", "TestParallel"},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.id, objects[i].ID)
+		assert.Equal(t, e.status, objects[i].Status, e.test)
+		assert.Equal(t, e.test, objects[i].GoTestName)
+	}
+}
+
+func TestConverter_Ambiguous(t *testing.T) {
+	markers, err := marker.ParseList([]string{"default", "structured"})
+	require.NoError(t, err)
+
+	objects := convert(t, Converter{Markers: markers}, `{"Action":"run","Package":"pkg","Test":"TestAmbiguous"}
+{"Action":"output","Package":"pkg","Test":"TestAmbiguous","Output":"    ambiguous_test.go:10: C301 First case\n"}
+{"Action":"output","Package":"pkg","Test":"TestAmbiguous","Output":"    ambiguous_test.go:11: C301 Renamed case\n"}
+{"Action":"output","Package":"pkg","Test":"TestAmbiguous","Output":"    ambiguous_test.go:12: testrail:case=302 title=\"Second\" C303 Third\n"}
+{"Action":"pass","Package":"pkg","Test":"TestAmbiguous","Elapsed":0.1}
+`)
+
+	require.Len(t, objects, 1)
+	assert.Equal(t, 301, objects[0].ID)
+	assert.Equal(t, []string{
+		"ambiguous_test.go:11: C301 Renamed case",
+		`ambiguous_test.go:12: testrail:case=302 title="Second" C303 Third`,
+	}, objects[0].Ambiguous)
+}
+
+func TestConverter_JUnit(t *testing.T) {
+	f, err := os.Open("../../../parser/junit/example_test.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	objects := Converter{}.ConvertEventsToMatcherObjects(junit.Parser{}.GetParseIterator(f))
+	titles := make(map[int]string)
+	for _, o := range objects {
+		// case taken from test name isn't matched again in status line
+		assert.Empty(t, o.Ambiguous, o.GoTestName)
+		if o.ID != 0 {
+			titles[o.ID] = o.Description
+		}
+	}
+	assert.Equal(t, map[int]string{
+		9999: "Pass test",
+		3606: "Fail testsdf",
+		3607: "Skip test",
+		3703: "Login with valid password",
+		3704: "Login with invalid password",
+	}, titles)
+}
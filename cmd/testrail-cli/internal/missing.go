@@ -0,0 +1,102 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+// MissingCase is case to be created for test which logged no case ID
+type MissingCase struct {
+	Package string
+	Test    string
+	// Section is path of section names mirroring package path
+	Section []string
+	Title   string
+	CaseID  int
+}
+
+// MissingCases returns cases for tests without case ID, prefix is trimmed from package
+// path before it is split to section names, ex.: module path; test with subtests
+// without case ID is skipped, cases are created for subtests
+func MissingCases(notFound []*types.TestMatcher, prefix string) []MissingCase {
+	var (
+		missing []MissingCase
+		seen    = make(map[string]bool)
+		parents = make(map[string]bool)
+	)
+	for _, o := range notFound {
+		if o.ID != 0 {
+			continue
+		}
+		for test := o.GoTestName; strings.Contains(test, "/"); {
+			test = test[:strings.LastIndex(test, "/")]
+			parents[o.Package+"|"+test] = true
+		}
+	}
+
+	for _, o := range notFound {
+		key := o.Package + "|" + o.GoTestName
+		if o.ID != 0 || seen[key] || parents[key] {
+			continue
+		}
+		seen[key] = true
+
+		var section []string
+		rel := strings.Trim(strings.TrimPrefix(o.Package, prefix), "/")
+		if rel != "" {
+			section = strings.Split(rel, "/")
+		}
+		missing = append(missing, MissingCase{
+			Package: o.Package,
+			Test:    o.GoTestName,
+			Section: section,
+			Title:   o.GoTestName,
+		})
+	}
+	return missing
+}
+
+// ModulePath returns module path from go.mod of dir or its parents, it is empty
+// if there is no go.mod
+func ModulePath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if module, ok := modulePath(filepath.Join(root, "go.mod")); ok {
+			return module
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// PrintAnnotations writes t.Log lines to be added to tests for created cases, grouped by package
+func PrintAnnotations(w io.Writer, created []MissingCase) error {
+	pkg := ""
+	for i, c := range created {
+		if i == 0 || c.Package != pkg {
+			pkg = c.Package
+			if _, err := fmt.Fprintln(w, pkg); err != nil {
+				return err
+			}
+		}
+		line := strconv.Quote(fmt.Sprintf("C%d %s", c.CaseID, c.Title))
+		if _, err := fmt.Fprintf(w, "  %s: t.Log(%s)\n", c.Test, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+func TestMissingCases(t *testing.T) {
+	notFound := []*types.TestMatcher{
+		{Package: "example.com/mod/parser/json", GoTestName: "TestParse"},
+		{Package: "example.com/mod/parser/json", GoTestName: "TestParse"},
+		{Package: "example.com/mod/parser/json", GoTestName: "TestKnown", ID: 7},
+		{Package: "example.com/mod", GoTestName: "TestRoot"},
+		{Package: "example.com/mod/client", GoTestName: "TestGroup"},
+		{Package: "example.com/mod/client", GoTestName: "TestGroup/first"},
+		{Package: "example.com/mod/client", GoTestName: "TestGroup/second"},
+	}
+
+	assert.Equal(t, []MissingCase{
+		{Package: "example.com/mod/parser/json", Test: "TestParse", Section: []string{"parser", "json"}, Title: "TestParse"},
+		{Package: "example.com/mod", Test: "TestRoot", Title: "TestRoot"},
+		{Package: "example.com/mod/client", Test: "TestGroup/first", Section: []string{"client"}, Title: "TestGroup/first"},
+		{Package: "example.com/mod/client", Test: "TestGroup/second", Section: []string{"client"}, Title: "TestGroup/second"},
+	}, MissingCases(notFound, "example.com/mod"))
+}
+
+func TestPrintAnnotations(t *testing.T) {
+	var b strings.Builder
+	err := PrintAnnotations(&b, []MissingCase{
+		{Package: "example.com/mod/a", Test: "TestA", Title: "TestA", CaseID: 1},
+		{Package: "example.com/mod/a", Test: "TestB/sub", Title: "TestB/sub", CaseID: 2},
+		{Package: "example.com/mod/b", Test: "TestC", Title: "TestC", CaseID: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `example.com/mod/a
+  TestA: t.Log("C1 TestA")
+  TestB/sub: t.Log("C2 TestB/sub")
+example.com/mod/b
+  TestC: t.Log("C3 TestC")
+`, b.String())
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal/logfmt"
+	"github.com/insolar/testrail-cli/issue"
+	"github.com/insolar/testrail-cli/marker"
+	"github.com/insolar/testrail-cli/parser"
+	"github.com/insolar/testrail-cli/parser/convlog"
+	"github.com/insolar/testrail-cli/parser/json"
+	"github.com/insolar/testrail-cli/parser/junit"
+	"github.com/insolar/testrail-cli/parser/text"
+	"github.com/insolar/testrail-cli/types"
+)
+
+// EventConverter builds test matchers from parsed test events
+type EventConverter interface {
+	ConvertEventsToMatcherObjects(reader parser.EventReader) []*types.TestMatcher
+}
+
+// ConverterOptions are settings of converters, converter uses ones it supports
+type ConverterOptions struct {
+	MaxOutputSize int
+	Issues        issue.Matcher
+	Markers       marker.Set
+	Mapping       map[string][]types.CaseRef
+}
+
+// ConverterInfo creates converter, Titles tells whether converter finds case titles,
+// so they could be checked against testrail
+type ConverterInfo struct {
+	New    func(ConverterOptions) EventConverter
+	Titles bool
+}
+
+var (
+	// Parsers are test output parsers by --FORMAT name
+	Parsers = map[string]parser.Parser{
+		"json":    json.Parser{},
+		"text":    text.Parser{},
+		"junit":   junit.Parser{},
+		"convlog": convlog.Parser{},
+	}
+
+	// Converters are converters by --MATCHER name
+	Converters = map[string]ConverterInfo{
+		"default": {
+			New: func(o ConverterOptions) EventConverter {
+				return Converter{MaxOutputSize: o.MaxOutputSize, Issues: o.Issues, Markers: o.Markers, Mapping: o.Mapping}
+			},
+			Titles: true,
+		},
+		"logfmt": {
+			New: func(o ConverterOptions) EventConverter {
+				return logfmt.Converter{Issues: o.Issues}
+			},
+		},
+	}
+)
+
+// LookupParser returns parser registered with name
+func LookupParser(name string) (parser.Parser, error) {
+	p, ok := Parsers[name]
+	if !ok {
+		names := make([]string, 0, len(Parsers))
+		for n := range Parsers {
+			names = append(names, n)
+		}
+		return nil, fmt.Errorf("unsupported format %s, use one of %s", name, joinSorted(names))
+	}
+	return p, nil
+}
+
+// LookupConverter returns converter registered with name
+func LookupConverter(name string) (ConverterInfo, error) {
+	c, ok := Converters[name]
+	if !ok {
+		names := make([]string, 0, len(Converters))
+		for n := range Converters {
+			names = append(names, n)
+		}
+		return ConverterInfo{}, fmt.Errorf("unsupported matcher %s, use one of %s", name, joinSorted(names))
+	}
+	return c, nil
+}
+
+func joinSorted(names []string) string {
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+const assuredLedgerLog = `=== RUN   TestConstructor
+2020-07-20T19:19:01.801055000+03:00 INF testrail ID=C5005 Status=PASS TestName=TestConstructor TestPackage=example.com/ledger caller=testrail.go:126
+--- PASS: TestConstructor (0.10s)
+=== RUN   TestPanicked
+2020-07-20T19:19:01.801055000+03:00 INF testrail ID=C5006 TestName=TestPanicked TestPackage=example.com/ledger
+--- FAIL: TestPanicked (0.10s)
+FAIL
+`
+
+func TestRegistry_Logfmt(t *testing.T) {
+	p, err := LookupParser("convlog")
+	require.NoError(t, err)
+	c, err := LookupConverter("logfmt")
+	require.NoError(t, err)
+	assert.False(t, c.Titles)
+
+	matchers := c.New(ConverterOptions{}).ConvertEventsToMatcherObjects(p.GetParseIterator(strings.NewReader(assuredLedgerLog)))
+	sort.Slice(matchers, func(i, j int) bool { return matchers[i].ID < matchers[j].ID })
+	require.Len(t, matchers, 2)
+	assert.Equal(t, types.TestMatcher{ID: 5005, Status: types.TestStatusPassed, GoTestName: "TestConstructor"}, *matchers[0])
+	// test which logged no status is failed
+	assert.Equal(t, types.TestStatusFailed, matchers[1].Status)
+}
+
+func TestRegistry_Unknown(t *testing.T) {
+	_, err := LookupParser("xml")
+	assert.EqualError(t, err, "unsupported format xml, use one of convlog, json, junit, text")
+	_, err = LookupConverter("regex")
+	assert.EqualError(t, err, "unsupported matcher regex, use one of default, logfmt")
+
+	c, err := LookupConverter("default")
+	require.NoError(t, err)
+	assert.True(t, c.Titles)
+}
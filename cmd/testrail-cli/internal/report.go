@@ -0,0 +1,257 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+// Report is test objects summary written to file for CI bots
+type Report struct {
+	RunID          int            `json:"run_id,omitempty"`
+	RunURL         string         `json:"run_url,omitempty"`
+	Counts         map[string]int `json:"counts"`
+	Valid          []ReportEntry  `json:"valid"`
+	NotFound       []ReportEntry  `json:"not_found"`
+	WrongDesc      []ReportEntry  `json:"wrong_desc"`
+	SkippedNoIssue []ReportEntry  `json:"skipped_no_issue"`
+	Ambiguous      []ReportEntry  `json:"ambiguous"`
+}
+
+// ReportEntry is test and testrail case it is matched to
+type ReportEntry struct {
+	Test                string   `json:"test"`
+	Status              string   `json:"status"`
+	CaseID              int      `json:"case_id,omitempty"`
+	CaseURL             string   `json:"case_url,omitempty"`
+	Description         string   `json:"description,omitempty"`
+	OriginalDescription string   `json:"original_description,omitempty"`
+	IssueURL            string   `json:"issue_url,omitempty"`
+	Elapsed             float64  `json:"elapsed"`
+	AmbiguousLines      []string `json:"ambiguous_lines,omitempty"`
+}
+
+// NewReport builds report from summary, counts are per status of all found tests
+func NewReport(s *TestObjectSummary, f URLFormatter, runID int, runURL string) Report {
+	entries := func(objects []*types.TestMatcher) []ReportEntry {
+		list := make([]ReportEntry, 0, len(objects))
+		for _, o := range objects {
+			e := ReportEntry{
+				Test:                o.GoTestName,
+				Status:              o.Status,
+				CaseID:              o.ID,
+				Description:         o.Description,
+				OriginalDescription: o.OriginalDescription,
+				IssueURL:            o.IssueURL,
+				Elapsed:             o.Elapsed,
+				AmbiguousLines:      o.Ambiguous,
+			}
+			if o.ID != 0 {
+				e.CaseURL = f.FormatURL(o.ID)
+			}
+			list = append(list, e)
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Test < list[j].Test
+		})
+		return list
+	}
+
+	r := Report{
+		RunID:          runID,
+		RunURL:         runURL,
+		Counts:         make(map[string]int),
+		Valid:          entries(s.Valid),
+		NotFound:       entries(s.NotFound),
+		WrongDesc:      entries(s.WrongDesc),
+		SkippedNoIssue: entries(s.SkippedNoIssue),
+		Ambiguous:      entries(s.Ambiguous),
+	}
+	// skipped tests without issue and ambiguous tests are in other groups as well
+	for _, group := range [][]ReportEntry{r.Valid, r.NotFound, r.WrongDesc} {
+		for _, e := range group {
+			r.Counts[e.Status]++
+		}
+	}
+	return r
+}
+
+// Write writes report in json, markdown or junit format
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "markdown":
+		_, err := io.WriteString(w, r.markdown())
+		return err
+	case "junit":
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(r.junit()); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	default:
+		return fmt.Errorf("unsupported report format %s", format)
+	}
+}
+
+func (r Report) statuses() []string {
+	statuses := make([]string, 0, len(r.Counts))
+	for status := range r.Counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+// markdownEscape keeps test names and titles from breaking table
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func markdownCase(e ReportEntry) string {
+	if e.CaseURL == "" {
+		return fmt.Sprintf("C%d", e.CaseID)
+	}
+	return fmt.Sprintf("[C%d](%s)", e.CaseID, e.CaseURL)
+}
+
+func (r Report) markdown() string {
+	var b strings.Builder
+
+	b.WriteString("## TestRail report\n\n")
+	if r.RunID != 0 {
+		if r.RunURL != "" {
+			fmt.Fprintf(&b, "Run: [%d](%s)\n\n", r.RunID, r.RunURL)
+		} else {
+			fmt.Fprintf(&b, "Run: %d\n\n", r.RunID)
+		}
+	}
+
+	b.WriteString("| Status | Tests |\n| --- | --- |\n")
+	for _, status := range r.statuses() {
+		fmt.Fprintf(&b, "| %s | %d |\n", status, r.Counts[status])
+	}
+	fmt.Fprintf(&b, "\n%d valid, %d not found in testrail, %d with wrong title, %d skipped without issue\n",
+		len(r.Valid), len(r.NotFound), len(r.WrongDesc), len(r.SkippedNoIssue))
+
+	if len(r.NotFound) > 0 {
+		b.WriteString("\n### Tests not found in testrail\n\n| Test | Case | Status |\n| --- | --- | --- |\n")
+		for _, e := range r.NotFound {
+			c := "-"
+			if e.CaseID != 0 {
+				c = fmt.Sprintf("C%d", e.CaseID)
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", markdownEscape(e.Test), c, e.Status)
+		}
+	}
+	if len(r.WrongDesc) > 0 {
+		b.WriteString("\n### Test title discrepancy with testrail test-case title\n\n" +
+			"| Test | Case | Test title | TestRail title |\n| --- | --- | --- | --- |\n")
+		for _, e := range r.WrongDesc {
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", markdownEscape(e.Test), markdownCase(e),
+				markdownEscape(e.Description), markdownEscape(e.OriginalDescription))
+		}
+	}
+	if len(r.SkippedNoIssue) > 0 {
+		b.WriteString("\n### Skipped tests without issue\n\n| Test |\n| --- |\n")
+		for _, e := range r.SkippedNoIssue {
+			fmt.Fprintf(&b, "| `%s` |\n", markdownEscape(e.Test))
+		}
+	}
+	if len(r.Ambiguous) > 0 {
+		b.WriteString("\n### Tests with ambiguous case references\n\n| Test | Line |\n| --- | --- |\n")
+		for _, e := range r.Ambiguous {
+			for _, line := range e.AmbiguousLines {
+				fmt.Fprintf(&b, "| `%s` | `%s` |\n", markdownEscape(e.Test), markdownEscape(line))
+			}
+		}
+	}
+	if len(r.Valid) > 0 {
+		b.WriteString("\n<details>\n<summary>Uploaded results</summary>\n\n| Test | Case | Status |\n| --- | --- | --- |\n")
+		for _, e := range r.Valid {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", markdownEscape(e.Test), markdownCase(e), e.Status)
+		}
+		b.WriteString("\n</details>\n")
+	}
+	return b.String()
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junit reports every test as testcase of testrail check, tests with mapping problems fail
+func (r Report) junit() junitSuite {
+	suite := junitSuite{Name: "testrail"}
+	add := func(class string, entries []ReportEntry, failure func(ReportEntry) *junitFailure) {
+		for _, e := range entries {
+			c := junitCase{
+				Name:      e.Test,
+				ClassName: "testrail." + class,
+				Time:      fmt.Sprintf("%.2f", e.Elapsed),
+			}
+			if failure != nil {
+				c.Failure = failure(e)
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+	}
+
+	add("valid", r.Valid, nil)
+	add("not_found", r.NotFound, func(e ReportEntry) *junitFailure {
+		if e.CaseID != 0 {
+			return &junitFailure{Message: fmt.Sprintf("C%d is not found in testrail", e.CaseID)}
+		}
+		return &junitFailure{Message: "test has no testrail case ID"}
+	})
+	add("wrong_desc", r.WrongDesc, func(e ReportEntry) *junitFailure {
+		return &junitFailure{
+			Message: fmt.Sprintf("C%d title differs from testrail", e.CaseID),
+			Text: fmt.Sprintf("Test Description:     %s\nOriginal Description: %s\nTestCase URL:         %s",
+				e.Description, e.OriginalDescription, e.CaseURL),
+		}
+	})
+	add("skipped_no_issue", r.SkippedNoIssue, func(e ReportEntry) *junitFailure {
+		return &junitFailure{Message: "skipped test has no issue"}
+	})
+	add("ambiguous", r.Ambiguous, func(e ReportEntry) *junitFailure {
+		return &junitFailure{Message: "test has ambiguous case references", Text: strings.Join(e.AmbiguousLines, "\n")}
+	})
+	suite.Tests = len(suite.Cases)
+	return suite
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+type testURLFormatter struct{}
+
+func (testURLFormatter) FormatURL(id int) string {
+	return "https://testrail/index.php?/cases/view/" + strconv.Itoa(id)
+}
+
+func testSummary() *TestObjectSummary {
+	skipped := &types.TestMatcher{GoTestName: "TestSkipped", Status: "SKIP"}
+	return &TestObjectSummary{
+		Valid: []*types.TestMatcher{
+			{ID: 1, GoTestName: "TestPass", Status: "PASS", Description: "Pass"},
+			{ID: 2, GoTestName: "TestFail", Status: "FAIL", Description: "Fail"},
+		},
+		NotFound:       []*types.TestMatcher{skipped},
+		WrongDesc:      []*types.TestMatcher{{ID: 3, GoTestName: "TestTitle", Status: "PASS", Description: "New", OriginalDescription: "Old"}},
+		SkippedNoIssue: []*types.TestMatcher{skipped},
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport(testSummary(), testURLFormatter{}, 54, "https://testrail/index.php?/runs/view/54")
+	assert.Equal(t, map[string]int{"PASS": 2, "FAIL": 1, "SKIP": 1}, report.Counts)
+	assert.Equal(t, "https://testrail/index.php?/cases/view/3", report.WrongDesc[0].CaseURL)
+	assert.Empty(t, report.NotFound[0].CaseURL)
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.Write(&buf, "json"))
+
+		var decoded Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, report, decoded)
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.Write(&buf, "markdown"))
+		assert.Contains(t, buf.String(), "| `TestTitle` | [C3](https://testrail/index.php?/cases/view/3) | New | Old |")
+		assert.Contains(t, buf.String(), "2 valid, 1 not found in testrail, 1 with wrong title, 1 skipped without issue")
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.Write(&buf, "junit"))
+		assert.Contains(t, buf.String(), `<testsuite name="testrail" tests="5" failures="3">`)
+		assert.Contains(t, buf.String(), `<failure message="C3 title differs from testrail">`)
+	})
+
+	assert.Error(t, report.Write(&bytes.Buffer{}, "html"))
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const commitLen = 8
+
+var (
+	// CI checkouts usually have detached HEAD, so environment is checked first
+	branchEnvs = []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "BRANCH_NAME", "GIT_BRANCH"}
+	commitEnvs = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "GIT_COMMIT"}
+)
+
+func fromEnvOrGit(envs []string, gitArgs ...string) string {
+	for _, env := range envs {
+		if val := os.Getenv(env); val != "" {
+			return val
+		}
+	}
+
+	out, err := exec.Command("git", gitArgs...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// FormatRunName expands {branch}, {commit} and {date} placeholders of run name template
+func FormatRunName(template string, now time.Time) string {
+	replacements := []string{"{date}", now.Format("2006-01-02 15:04")}
+
+	if strings.Contains(template, "{branch}") {
+		branch := fromEnvOrGit(branchEnvs, "rev-parse", "--abbrev-ref", "HEAD")
+		replacements = append(replacements, "{branch}", branch)
+	}
+	if strings.Contains(template, "{commit}") {
+		commit := CurrentCommit()
+		if len(commit) > commitLen {
+			commit = commit[:commitLen]
+		}
+		replacements = append(replacements, "{commit}", commit)
+	}
+
+	return strings.Join(strings.Fields(strings.NewReplacer(replacements...).Replace(template)), " ")
+}
+
+// CurrentCommit returns commit hash from CI environment or git, it is empty outside of repository
+func CurrentCommit() string {
+	return fromEnvOrGit(commitEnvs, "rev-parse", "HEAD")
+}
+
+// ResolveVersion returns result version for spec, spec is literal version, "git" for
+// git describe output or env:NAME for value of environment variable
+func ResolveVersion(spec string) (string, error) {
+	switch {
+	case spec == "git":
+		out, err := exec.Command("git", "describe", "--tags", "--always", "--dirty").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to describe version with git: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	case strings.HasPrefix(spec, "env:"):
+		name := strings.TrimPrefix(spec, "env:")
+		version := os.Getenv(name)
+		if version == "" {
+			return "", fmt.Errorf("version environment variable %s is not set", name)
+		}
+		return version, nil
+	default:
+		return spec, nil
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setEnv sets environment for test, variables with empty value are unset,
+// returned func restores previous environment
+func setEnv(t *testing.T, env map[string]string) func() {
+	prev := make(map[string]*string)
+	for name, val := range env {
+		if old, ok := os.LookupEnv(name); ok {
+			prev[name] = &old
+		} else {
+			prev[name] = nil
+		}
+		if val == "" {
+			require.NoError(t, os.Unsetenv(name))
+		} else {
+			require.NoError(t, os.Setenv(name, val))
+		}
+	}
+	return func() {
+		for name, old := range prev {
+			if old == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *old)
+			}
+		}
+	}
+}
+
+func ciEnv(branch, commit string) map[string]string {
+	env := make(map[string]string)
+	for _, name := range append(branchEnvs, commitEnvs...) {
+		env[name] = ""
+	}
+	env["CI_COMMIT_REF_NAME"] = branch
+	env["CI_COMMIT_SHA"] = commit
+	return env
+}
+
+func TestFormatRunName(t *testing.T) {
+	now := time.Date(2020, 3, 14, 15, 9, 26, 0, time.UTC)
+
+	t.Run("env", func(t *testing.T) {
+		defer setEnv(t, ciEnv("feature/runs", "0123456789abcdef"))()
+
+		assert.Equal(t, "nightly feature/runs 01234567 2020-03-14 15:09",
+			FormatRunName("nightly {branch} {commit} {date}", now))
+		assert.Equal(t, "nightly 2020-03-14 15:09", FormatRunName("  nightly   {date} ", now))
+	})
+
+	t.Run("short commit", func(t *testing.T) {
+		defer setEnv(t, ciEnv("master", "abc"))()
+
+		assert.Equal(t, "master abc", FormatRunName("{branch} {commit}", now))
+	})
+
+	t.Run("git", func(t *testing.T) {
+		defer setEnv(t, ciEnv("", ""))()
+
+		out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+		if err != nil {
+			t.Skip("git repository is not available")
+		}
+		assert.Equal(t, "run "+strings.TrimSpace(string(out))[:commitLen], FormatRunName("run {commit}", now))
+	})
+
+	t.Run("no git", func(t *testing.T) {
+		defer setEnv(t, map[string]string{"PATH": os.TempDir()})()
+		defer setEnv(t, ciEnv("", ""))()
+
+		assert.Equal(t, "run", FormatRunName("run {branch} {commit}", now))
+	})
+}
@@ -0,0 +1,340 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/insolar/testrail-cli/marker"
+	tparser "github.com/insolar/testrail-cli/parser"
+	"github.com/insolar/testrail-cli/types"
+)
+
+// Mapping is static inventory of cases claimed by tests, it is written by scan command
+type Mapping struct {
+	Tests []MappedTest `json:"tests"`
+}
+
+// MappedTest is test or subtest with cases it logs
+type MappedTest struct {
+	Package string          `json:"package"`
+	Test    string          `json:"test"`
+	File    string          `json:"file"`
+	Line    int             `json:"line"`
+	Cases   []types.CaseRef `json:"cases"`
+}
+
+// LoadMapping reads mapping written by scan command
+func LoadMapping(r io.Reader) (Mapping, error) {
+	var m Mapping
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return Mapping{}, fmt.Errorf("failed to decode mapping: %w", err)
+	}
+	return m, nil
+}
+
+// Write writes mapping as json
+func (m Mapping) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Cases returns mapped cases by test key, see parser.UniqueTestKeyFromFields
+func (m Mapping) Cases() map[string][]types.CaseRef {
+	cases := make(map[string][]types.CaseRef)
+	for _, t := range m.Tests {
+		key := tparser.UniqueTestKeyFromFields(t.Package, t.Test)
+		cases[key] = append(cases[key], t.Cases...)
+	}
+	return cases
+}
+
+// Scan finds cases logged by Test functions in go source, patterns are directories,
+// "dir/..." scans directory recursively
+func Scan(patterns []string, markers marker.Set) (Mapping, error) {
+	dirs, err := expandDirs(patterns)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	var mapping Mapping
+	for _, dir := range dirs {
+		tests, err := scanDir(dir, markers)
+		if err != nil {
+			return Mapping{}, err
+		}
+		mapping.Tests = append(mapping.Tests, tests...)
+	}
+	sort.SliceStable(mapping.Tests, func(i, j int) bool {
+		a, b := mapping.Tests[i], mapping.Tests[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Test < b.Test
+	})
+	return mapping, nil
+}
+
+// expandDirs returns directories matching patterns, "dir/..." matches directory recursively
+func expandDirs(patterns []string) ([]string, error) {
+	var dirs []string
+	for _, pattern := range patterns {
+		if !strings.HasSuffix(pattern, "...") {
+			dirs = append(dirs, pattern)
+			continue
+		}
+
+		root := filepath.Clean(strings.TrimSuffix(pattern, "..."))
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			// go tool ignores these directories as well
+			if name := info.Name(); path != root && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			dirs = append(dirs, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+func scanDir(dir string, markers marker.Set) ([]MappedTest, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	pkg, err := importPath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tests []MappedTest
+		fset  = token.NewFileSet()
+	)
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		s := &scanner{fset: fset, markers: markers, pkg: pkg, tests: make(map[string]*MappedTest)}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Body == nil || !isTestFunc(fn) {
+				continue
+			}
+			s.annotations(fn.Name.Name, fn.Doc)
+			s.walk(fn.Name.Name, fn.Body)
+		}
+		for _, name := range s.order {
+			tests = append(tests, *s.tests[name])
+		}
+	}
+	return tests, nil
+}
+
+// isTestFunc reports whether function is TestXxx(t *testing.T)
+func isTestFunc(fn *ast.FuncDecl) bool {
+	name := fn.Name.Name
+	if !strings.HasPrefix(name, "Test") || name == "TestMain" {
+		return false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "T"
+}
+
+type scanner struct {
+	fset    *token.FileSet
+	markers marker.Set
+	pkg     string
+	tests   map[string]*MappedTest
+	order   []string
+}
+
+// annotations finds cases in doc comment of test, ex.: "// C3605 Login" or "// testrail:case=3605",
+// so test claims case before it logs anything
+func (s *scanner) annotations(test string, doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	for _, c := range doc.List {
+		// CommentGroup.Text drops directive-like lines, "//testrail:case=1" among them
+		text := strings.TrimPrefix(c.Text, "//")
+		if strings.HasPrefix(c.Text, "/*") {
+			text = strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+		}
+		for _, line := range strings.Split(text, "\n") {
+			for _, match := range s.markers.Find(line) {
+				s.add(test, c.Pos(), match.Ref)
+			}
+		}
+	}
+}
+
+// walk finds t.Log calls of test and literals passed to helpers taking t, ex.: checkGroup(t, "C3702 Create group"),
+// subtests run with literal name are walked separately, cases of subtests with computed name are attributed to the test
+func (s *scanner) walk(test string, body ast.Node) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		method := ""
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			method = sel.Sel.Name
+		}
+		switch method {
+		case "Run":
+			if len(call.Args) != 2 {
+				return true
+			}
+			name, ok := stringLit(call.Args[0])
+			fn, isFunc := call.Args[1].(*ast.FuncLit)
+			if !ok || !isFunc {
+				return true
+			}
+			s.walk(test+"/"+subtestName(name), fn.Body)
+			return false
+		case "Log", "Logf":
+			if len(call.Args) > 0 {
+				s.addLiteral(test, call.Args[0])
+			}
+		default:
+			if len(call.Args) > 1 && isIdent(call.Args[0], "t") {
+				for _, arg := range call.Args[1:] {
+					s.addLiteral(test, arg)
+				}
+			}
+		}
+		return true
+	})
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+func (s *scanner) addLiteral(test string, expr ast.Expr) {
+	text, ok := stringLit(expr)
+	if !ok {
+		return
+	}
+	for _, match := range s.markers.Find(text) {
+		s.add(test, expr.Pos(), match.Ref)
+	}
+}
+
+func (s *scanner) add(test string, pos token.Pos, ref types.CaseRef) {
+	t, ok := s.tests[test]
+	if !ok {
+		position := s.fset.Position(pos)
+		t = &MappedTest{
+			Package: s.pkg,
+			Test:    test,
+			File:    filepath.ToSlash(position.Filename),
+			Line:    position.Line,
+		}
+		s.tests[test] = t
+		s.order = append(s.order, test)
+	}
+	for _, c := range t.Cases {
+		if c.ID == ref.ID {
+			return
+		}
+	}
+	t.Cases = append(t.Cases, ref)
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// subtestName rewrites subtest name like testing package does
+func subtestName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// importPath returns import path of package in dir using module path from go.mod
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if module, ok := modulePath(filepath.Join(root, "go.mod")); ok {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return module, nil
+			}
+			return module + "/" + filepath.ToSlash(rel), nil
+		}
+		if filepath.Dir(root) == root {
+			return filepath.ToSlash(dir), nil
+		}
+	}
+}
+
+func modulePath(gomod string) (string, bool) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if strings.HasPrefix(line, "module") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/marker"
+	"github.com/insolar/testrail-cli/types"
+)
+
+const scanPackage = "github.com/insolar/testrail-cli/cmd/testrail-cli/internal/testdata/scan"
+
+func TestScan(t *testing.T) {
+	mapping, err := Scan([]string{"testdata/scan"}, marker.Default)
+	require.NoError(t, err)
+
+	tests := make(map[string][]types.CaseRef)
+	for _, test := range mapping.Tests {
+		assert.Equal(t, scanPackage, test.Package)
+		tests[test.Test] = test.Cases
+	}
+	assert.Equal(t, map[string][]types.CaseRef{
+		"TestLogged":              {{ID: 101, Description: "Logged case"}},
+		"TestAnnotated":           {{ID: 106, Description: "Annotated case"}, {ID: 101, Description: "Logged case"}},
+		"TestSubtests/first_case": {{ID: 102, Description: "First subtest"}},
+		"TestSubtests": {
+			{ID: 103, Description: "Computed subtest"},
+			{ID: 104, Description: "Helper case"},
+		},
+	}, tests)
+
+	var buf bytes.Buffer
+	require.NoError(t, mapping.Write(&buf))
+	loaded, err := LoadMapping(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, mapping, loaded)
+}
+
+func TestConverter_Mapping(t *testing.T) {
+	mapping := Mapping{Tests: []MappedTest{
+		{Package: "pkg", Test: "TestPanic", Cases: []types.CaseRef{{ID: 401, Description: "Panicked"}}},
+		{Package: "pkg", Test: "TestLogged", Cases: []types.CaseRef{{ID: 402, Description: "Stale"}}},
+	}}
+
+	objects := convert(t, Converter{Mapping: mapping.Cases()}, `{"Action":"run","Package":"pkg","Test":"TestPanic"}
+{"Action":"output","Package":"pkg","Test":"TestPanic","Output":"panic: runtime error\n"}
+{"Action":"fail","Package":"pkg","Test":"TestPanic","Elapsed":0.1}
+{"Action":"run","Package":"pkg","Test":"TestLogged"}
+{"Action":"output","Package":"pkg","Test":"TestLogged","Output":"    logged_test.go:5: C403 Logged\n"}
+{"Action":"pass","Package":"pkg","Test":"TestLogged","Elapsed":0.1}
+`)
+
+	require.Len(t, objects, 2)
+	assert.Equal(t, 401, objects[0].ID)
+	assert.Equal(t, "FAIL", objects[0].Status)
+	assert.Equal(t, 403, objects[1].ID)
+}
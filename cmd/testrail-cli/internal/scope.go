@@ -0,0 +1,56 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+// matchPackage reports whether package matches pattern, "pkg/..." matches pkg and its subpackages
+func matchPackage(pattern, pkg string) bool {
+	if prefix := strings.TrimSuffix(pattern, "/..."); prefix != pattern {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pkg == pattern
+}
+
+func matchPackages(patterns []string, pkg string) bool {
+	for _, pattern := range patterns {
+		if matchPackage(pattern, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeCases returns sorted ids of cases claimed by tests of packages, tests are taken
+// from test output and from mapping, so cases of tests which didn't run are included
+func ScopeCases(packages []string, mapping Mapping, tests []*types.TestMatcher) []int {
+	seen := make(map[int]bool)
+	for _, t := range tests {
+		if t.ID != 0 && matchPackages(packages, t.Package) {
+			seen[t.ID] = true
+		}
+	}
+	for _, t := range mapping.Tests {
+		if !matchPackages(packages, t.Package) {
+			continue
+		}
+		for _, c := range t.Cases {
+			seen[c.ID] = true
+		}
+	}
+
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
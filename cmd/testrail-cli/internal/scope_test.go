@@ -0,0 +1,38 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+func TestMatchPackage(t *testing.T) {
+	assert.True(t, matchPackage("example.com/mod/parser", "example.com/mod/parser"))
+	assert.False(t, matchPackage("example.com/mod/parser", "example.com/mod/parser/json"))
+	assert.True(t, matchPackage("example.com/mod/parser/...", "example.com/mod/parser"))
+	assert.True(t, matchPackage("example.com/mod/parser/...", "example.com/mod/parser/json"))
+	assert.False(t, matchPackage("example.com/mod/parser/...", "example.com/mod/parserx"))
+}
+
+func TestScopeCases(t *testing.T) {
+	mapping := Mapping{Tests: []MappedTest{
+		{Package: "example.com/mod/parser/json", Test: "TestParse", Cases: []types.CaseRef{{ID: 3}, {ID: 4}}},
+		{Package: "example.com/mod/client", Test: "TestUpload", Cases: []types.CaseRef{{ID: 5}}},
+	}}
+	tests := []*types.TestMatcher{
+		{Package: "example.com/mod/parser", GoTestName: "TestParser", ID: 1},
+		{Package: "example.com/mod/parser", GoTestName: "TestNoCase"},
+		{Package: "example.com/mod/client", GoTestName: "TestClient", ID: 2},
+	}
+
+	assert.Equal(t, []int{1, 3, 4}, ScopeCases([]string{"example.com/mod/parser/..."}, mapping, tests))
+	assert.Equal(t, []int{2, 5}, ScopeCases([]string{"example.com/mod/client"}, mapping, tests))
+	assert.Equal(t, []int{}, ScopeCases([]string{"example.com/other"}, mapping, tests))
+}
@@ -20,6 +20,7 @@ type TestObjectSummary struct {
 	NotFound       []*types.TestMatcher
 	WrongDesc      []*types.TestMatcher
 	SkippedNoIssue []*types.TestMatcher
+	Ambiguous      []*types.TestMatcher
 }
 
 func (s TestObjectSummary) LogInvalidTests(f URLFormatter) {
@@ -40,6 +41,16 @@ func (s TestObjectSummary) LogInvalidTests(f URLFormatter) {
 		}
 	}
 
+	if len(s.Ambiguous) > 0 {
+		log.Println("Tests with ambiguous case references:")
+		for _, o := range s.Ambiguous {
+			log.Printf("  %s", o.GoTestName)
+			for _, line := range o.Ambiguous {
+				log.Printf("    %s", line)
+			}
+		}
+	}
+
 	if len(s.SkippedNoIssue) > 0 {
 		log.Println("Skipped tests without issue:")
 		for _, o := range s.SkippedNoIssue {
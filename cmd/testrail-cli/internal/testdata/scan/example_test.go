@@ -0,0 +1,33 @@
+package scan
+
+import "testing"
+
+func TestLogged(t *testing.T) {
+	t.Log("C101 Logged case")
+}
+
+func TestSubtests(t *testing.T) {
+	t.Run("first case", func(t *testing.T) {
+		t.Logf("C102 First subtest")
+	})
+	for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			t.Log("C103 Computed subtest")
+		})
+	}
+	check(t, "C104 Helper case")
+}
+
+func check(t *testing.T, title string) {
+	t.Log(title)
+}
+
+func TestMain(m *testing.M) {
+	log("C105 Not a test")
+}
+
+// C106 Annotated case
+// C101 Logged case
+func TestAnnotated(t *testing.T) {
+	t.Log("C106 Annotated case")
+}
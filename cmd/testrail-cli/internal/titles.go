@@ -0,0 +1,174 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/insolar/testrail-cli/marker"
+	"github.com/insolar/testrail-cli/types"
+)
+
+// TitleChange is case title which differs in test and testrail
+type TitleChange struct {
+	CaseID int
+	Tests  []string
+	// Test is title logged by test
+	Test string
+	// TestRail is title of testrail case
+	TestRail string
+}
+
+// TitleChanges collects title discrepancies by case, case logged by tests with different
+// titles can't be synced and is returned as error
+func TitleChanges(wrongDesc []*types.TestMatcher) ([]TitleChange, []error) {
+	var (
+		changes []TitleChange
+		errs    []error
+		byCase  = make(map[int]*TitleChange)
+		order   []int
+		broken  = make(map[int]bool)
+	)
+	for _, o := range wrongDesc {
+		c, ok := byCase[o.ID]
+		if !ok {
+			c = &TitleChange{CaseID: o.ID, Test: o.Description, TestRail: o.OriginalDescription}
+			byCase[o.ID] = c
+			order = append(order, o.ID)
+		} else if c.Test != o.Description && !broken[o.ID] {
+			broken[o.ID] = true
+			errs = append(errs, fmt.Errorf("C%d is logged with different titles: %q and %q", o.ID, c.Test, o.Description))
+		}
+		c.Tests = append(c.Tests, o.GoTestName)
+	}
+
+	sort.Ints(order)
+	for _, id := range order {
+		if !broken[id] {
+			changes = append(changes, *byCase[id])
+		}
+	}
+	return changes, errs
+}
+
+// SourcePatch returns unified diff which rewrites case titles in string literals of go test
+// files to testrail titles, patterns are directories like in Scan
+func SourcePatch(patterns []string, markers marker.Set, changes []TitleChange) (string, error) {
+	byCase := make(map[int]TitleChange)
+	for _, c := range changes {
+		byCase[c.CaseID] = c
+	}
+
+	files, err := testFiles(patterns)
+	if err != nil {
+		return "", err
+	}
+
+	var patch strings.Builder
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		fixed, err := rewriteTitles(file, src, markers, byCase)
+		if err != nil {
+			return "", err
+		}
+		if fixed != nil {
+			name := filepath.ToSlash(file)
+			patch.WriteString(unifiedDiff("a/"+name, "b/"+name, string(src), string(fixed)))
+		}
+	}
+	return patch.String(), nil
+}
+
+func testFiles(patterns []string) ([]string, error) {
+	dirs, err := expandDirs(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		found, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// rewriteTitles returns source with replaced titles or nil if nothing is changed
+func rewriteTitles(file string, src []byte, markers marker.Set, changes map[int]TitleChange) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	ast.Inspect(f, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+
+		replaced := value
+		for _, match := range markers.Find(value) {
+			c, ok := changes[match.Ref.ID]
+			if !ok || match.Ref.Description != c.Test {
+				continue
+			}
+			text := replaced[match.Start:match.End]
+			replaced = replaced[:match.Start] + strings.Replace(text, c.Test, c.TestRail, 1) + replaced[match.End:]
+			// only one reference per literal, positions of others are shifted now
+			break
+		}
+		if replaced == value {
+			return true
+		}
+
+		quoted := strconv.Quote(replaced)
+		if strings.HasPrefix(lit.Value, "`") && !strings.Contains(replaced, "`") {
+			quoted = "`" + replaced + "`"
+		}
+		edits = append(edits, edit{
+			start: fset.Position(lit.Pos()).Offset,
+			end:   fset.Position(lit.End()).Offset,
+			text:  quoted,
+		})
+		return true
+	})
+	if len(edits) == 0 {
+		return nil, nil
+	}
+
+	var out []byte
+	last := 0
+	for _, e := range edits {
+		out = append(out, src[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, src[last:]...), nil
+}
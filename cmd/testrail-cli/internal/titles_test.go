@@ -0,0 +1,63 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/marker"
+	"github.com/insolar/testrail-cli/types"
+)
+
+func TestTitleChanges(t *testing.T) {
+	changes, errs := TitleChanges([]*types.TestMatcher{
+		{ID: 2, GoTestName: "TestB", Description: "New", OriginalDescription: "Old"},
+		{ID: 1, GoTestName: "TestA", Description: "New A", OriginalDescription: "Old A"},
+		{ID: 2, GoTestName: "TestC", Description: "New", OriginalDescription: "Old"},
+		{ID: 3, GoTestName: "TestD", Description: "One", OriginalDescription: "Old"},
+		{ID: 3, GoTestName: "TestE", Description: "Another", OriginalDescription: "Old"},
+	})
+
+	assert.Equal(t, []TitleChange{
+		{CaseID: 1, Tests: []string{"TestA"}, Test: "New A", TestRail: "Old A"},
+		{CaseID: 2, Tests: []string{"TestB", "TestC"}, Test: "New", TestRail: "Old"},
+	}, changes)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "C3")
+}
+
+func TestSourcePatch(t *testing.T) {
+	patch, err := SourcePatch([]string{"testdata/scan"}, marker.Default, []TitleChange{
+		{CaseID: 101, Test: "Logged case", TestRail: "Logged \"quoted\" case"},
+		{CaseID: 104, Test: "Helper case", TestRail: "Helper case in testrail"},
+		{CaseID: 102, Test: "Other title", TestRail: "Not changed"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `--- a/testdata/scan/example_test.go
++++ b/testdata/scan/example_test.go
+@@ -3,7 +3,7 @@
+ import "testing"
+ 
+ func TestLogged(t *testing.T) {
+-	t.Log("C101 Logged case")
++	t.Log("C101 Logged \"quoted\" case")
+ }
+ 
+ func TestSubtests(t *testing.T) {
+@@ -15,7 +15,7 @@
+ 			t.Log("C103 Computed subtest")
+ 		})
+ 	}
+-	check(t, "C104 Helper case")
++	check(t, "C104 Helper case in testrail")
+ }
+ 
+ func check(t *testing.T, title string) {
+`, patch)
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"log"
+
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/testrail"
+	"github.com/insolar/testrail-cli/types"
+)
+
+// spool saves results testrail didn't accept because it is unreachable or which it could accept
+// despite failure, they are sent by replay command
+func spool(dir, url string, run testrail.SpoolRun, t *testrail.Uploader, validated bool, cause error) {
+	entry, err := testrail.NewSpoolEntry(url, run, t.Pending(), validated)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entry.Ambiguous = t.Ambiguous()
+	entry.Unresolved = t.Unresolved()
+	entry.Metadata = map[string]string{
+		"file":   viper.GetString("FILE"),
+		"format": viper.GetString("FORMAT"),
+		"error":  cause.Error(),
+	}
+
+	path, err := testrail.WriteSpool(dir, entry)
+	if err != nil {
+		log.Fatalf("%v, failed to spool results: %v", cause, err)
+	}
+	log.Printf("testrail request failed: %v", cause)
+	if entry.Ambiguous != nil {
+		log.Printf("%d results could be added despite failure, they are checked against run on replay",
+			len(entry.Ambiguous.CaseIDs))
+	}
+	log.Printf("%d results for %s are spooled to %s, send them later with replay command",
+		len(entry.Payload.Results), run, path)
+}
+
+// spoolUnchecked spools results, which weren't checked against run cases, as run isn't
+// initialized because testrail is unavailable, they are validated by replay, returns exit code
+func spoolUnchecked(t *testrail.Uploader, dir, url string, run testrail.SpoolRun, tObjects []*types.TestMatcher, cause error) int {
+	if !testrail.IsTemporary(cause) || dir == "" {
+		log.Fatal(cause)
+	}
+	t.AddTests(withCaseID(tObjects), false)
+	spool(dir, url, run, t, false, cause)
+	return 0
+}
+
+// replay sends spooled results, entry is rewritten after every uploaded batch,
+// so results already delivered are not sent again if replay is interrupted
+func replay() {
+	var (
+		url      = viper.GetString("URL")
+		user     = viper.GetString("USER")
+		pass     = viper.GetString("PASSWORD")
+		spoolDir = viper.GetString("SPOOL-DIR")
+	)
+	if spoolDir == "" {
+		log.Fatal("provide spool directory, ex.: --SPOOL-DIR=.testrail-spool")
+	}
+	if user == "" {
+		log.Fatal("provide user for TestRail authentication")
+	}
+	if pass == "" {
+		log.Fatal("provide password/token for TestRail authentication")
+	}
+
+	files, err := testrail.ReadSpool(spoolDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Printf("nothing to replay in %s", spoolDir)
+		return
+	}
+
+	failed := 0
+	for i := range files {
+		file := &files[i]
+		entryURL := url
+		if entryURL == "" {
+			entryURL = file.Entry.URL
+		}
+
+		t := newUploader(entryURL, user, pass)
+		if file.Entry.RunID == 0 {
+			err := t.ResolveRun(&file.Entry)
+			// run is saved before results are sent, so repeated replay doesn't create another one
+			if file.Entry.RunID != 0 || file.Entry.RunAmbiguous {
+				if saveErr := file.SaveRun(file.Entry.SpoolRun); saveErr != nil {
+					log.Fatal(saveErr)
+				}
+			}
+			if err != nil {
+				log.Printf("entry %s: %v", file.Entry.ID, err)
+				failed++
+				continue
+			}
+			log.Printf("entry %s: results are sent to run %d", file.Entry.ID, file.Entry.RunID)
+		}
+		if err := t.InitSpooled(file.Entry); err != nil {
+			log.Printf("entry %s: %v", file.Entry.ID, err)
+			failed++
+			continue
+		}
+		// settings are set to results now, they are saved with pending ones
+		file.Entry.Unresolved = nil
+		t.OnBatchSent(func() {
+			if err := file.Save(t.Pending(), t.Ambiguous()); err != nil {
+				log.Printf("entry %s: %v", file.Entry.ID, err)
+			}
+		})
+
+		if err := t.Upload(); err != nil {
+			log.Printf("entry %s: %v", file.Entry.ID, err)
+			// results of batches failed ambiguously are checked again by next replay
+			if err := file.Save(t.Pending(), t.Ambiguous()); err != nil {
+				log.Fatal(err)
+			}
+			failed++
+			continue
+		}
+		// results unknown to run are dropped on init, so entry is removed here
+		if err := file.Save(t.Pending(), nil); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("entry %s: %d results sent to run %d", file.Entry.ID, len(t.Payload().Results), file.Entry.RunID)
+	}
+
+	if failed > 0 {
+		log.Fatalf("%d of %d spooled entries are not replayed", failed, len(files))
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal"
+)
+
+// scan writes mapping of tests to cases they log, found in go source without running tests
+func scan() {
+	patterns := pflag.Args()[1:]
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	mapping, err := internal.Scan(patterns, caseMarkers())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if path := viper.GetString("MAPPING"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+	if err := mapping.Write(w); err != nil {
+		log.Fatal(err)
+	}
+
+	cases := 0
+	for _, t := range mapping.Tests {
+		cases += len(t.Cases)
+	}
+	log.Printf("%d tests claim %d cases", len(mapping.Tests), cases)
+}
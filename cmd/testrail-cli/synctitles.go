@@ -0,0 +1,145 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal"
+	"github.com/insolar/testrail-cli/testrail"
+)
+
+// syncTitles fixes test title discrepancy: updates testrail case titles to ones logged by tests
+// or prints patch rewriting titles in go source to testrail ones
+func syncTitles() {
+	var (
+		file  = viper.GetString("FILE")
+		cases = viper.GetString("CASES")
+		to    = viper.GetString("TO")
+	)
+
+	if !converterInfo().Titles {
+		log.Fatalf("matcher %s doesn't find test titles, they can't be synced", viper.GetString("MATCHER"))
+	}
+	switch to {
+	case "testrail":
+		if cases != "" {
+			log.Fatal("testrail titles can't be updated from cases json export, provide run id or project and suite ids")
+		}
+	case "source":
+	default:
+		log.Fatalf("Unsupported sync direction %s, use testrail or source", to)
+	}
+	t := initSuiteCases()
+	tObjects := convertTests(file)
+
+	summary := internal.FilterTestObjects(tObjects, t.GetCasesWithDescription(), false)
+	changes, errs := internal.TitleChanges(summary.WrongDesc)
+	for _, err := range errs {
+		log.Printf("%v, skipped", err)
+	}
+	if len(changes) == 0 {
+		log.Println("case titles are in sync")
+		return
+	}
+
+	if to == "source" {
+		dirs := pflag.Args()[1:]
+		if len(dirs) == 0 {
+			dirs = []string{"./..."}
+		}
+		patch, err := internal.SourcePatch(dirs, caseMarkers(), changes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(patch)
+		return
+	}
+
+	for _, c := range changes {
+		fmt.Printf("C%d %s\n  testrail: %s\n  test:     %s\n", c.CaseID, strings.Join(c.Tests, ", "), c.TestRail, c.Test)
+	}
+	if !viper.GetBool("YES") {
+		if file == "" {
+			log.Fatal("test output is read from stdin, confirm update with --YES")
+		}
+		fmt.Printf("Update %d case titles in TestRail? [y/N] ", len(changes))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			log.Println("case titles are not updated")
+			return
+		}
+	}
+
+	failed := 0
+	for _, c := range changes {
+		if err := t.UpdateCaseTitle(c.CaseID, c.Test); err != nil {
+			log.Print(err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d case titles are not updated", failed, len(changes))
+	}
+	log.Printf("%d case titles are updated", len(changes))
+}
+
+// initSuiteCases returns uploader with cases of run, project suite or cases json export
+func initSuiteCases() *testrail.Uploader {
+	var (
+		url     = viper.GetString("URL")
+		user    = viper.GetString("USER")
+		pass    = viper.GetString("PASSWORD")
+		runID   = viper.GetInt("RUN_ID")
+		project = viper.GetInt("PROJECT_ID")
+		suite   = viper.GetInt("SUITE_ID")
+		cases   = viper.GetString("CASES")
+	)
+	t := newUploader(url, user, pass)
+
+	if cases != "" {
+		f, err := os.Open(cases)
+		if err != nil {
+			log.Fatal(err)
+		}
+		caseList, err := testrail.LoadCases(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		t.InitWithCases(runID, caseList)
+		return t
+	}
+
+	if url == "" {
+		log.Fatal("provide TestRail url")
+	}
+	if runID == 0 && (project == 0 || suite == 0) {
+		log.Fatal("provide run id, ex.: --RUN_ID=54, or project and suite ids, ex.: --PROJECT_ID=3 --SUITE_ID=12")
+	}
+	if user == "" {
+		log.Fatal("provide user for TestRail authentication")
+	}
+	if pass == "" {
+		log.Fatal("provide password/token for TestRail authentication")
+	}
+
+	if runID != 0 {
+		if err := t.Init(runID); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := t.InitSuite(project, suite); err != nil {
+		log.Fatal(err)
+	}
+	return t
+}
@@ -7,71 +7,133 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	trlib "github.com/educlos/testrail"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal"
-	"github.com/insolar/testrail-cli/parser"
-	"github.com/insolar/testrail-cli/parser/json"
-	"github.com/insolar/testrail-cli/parser/text"
+	"github.com/insolar/testrail-cli/config"
+	"github.com/insolar/testrail-cli/issue"
+	"github.com/insolar/testrail-cli/marker"
 	"github.com/insolar/testrail-cli/testrail"
+	"github.com/insolar/testrail-cli/types"
 )
 
 func main() {
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("TR")
+	viper.SetEnvKeyReplacer(config.EnvKeyReplacer)
+	flag.String("CONFIG-FILE", "", "config file with profiles, "+config.FileName+" in current or home directory by default")
+	flag.String("PROFILE", "", "config file profile, default profile of file by default")
 	flag.String("URL", "", "testrail url")
 	flag.String("USER", "", "testrail username")
 	flag.String("PASSWORD", "", "testrail password/token")
 	flag.String("FILE", "", "go test json file")
 	flag.Int("RUN_ID", 0, "testrail run id")
+	flag.Int("PLAN_ID", 0, "testrail plan id, run is chosen by configuration")
+	flag.String("CONFIG", "", "testrail plan run configuration, ex.: \"Postgres, Linux\"")
+	flag.Int("PROJECT_ID", 0, "testrail project id, used to create run when run id is not provided")
+	flag.Int("SUITE_ID", 0, "testrail suite id, used to create run or plan entry when run id is not provided")
+	flag.Int("MILESTONE_ID", 0, "testrail milestone id of created run")
+	flag.String("MODE", string(testrail.ModePartial), "which run cases without test result are marked N/A: full - all, partial - none, scoped - owned by job")
+	flag.String("SCOPE-SECTIONS", "", "space separated section ids owned by job in scoped mode, subsections included")
+	flag.String("SCOPE-PACKAGES", "", "space separated packages owned by job in scoped mode, ex.: example.com/module/parser/..., cases claimed by their tests in input and mapping")
+	flag.Bool("CREATE-MISSING", false, "create cases for tests without case ID")
+	flag.Int("SECTION_ID", 0, "testrail section id, created cases are added to its subsections mirroring package path")
+	flag.String("PACKAGE-PREFIX", "", "package path prefix skipped in sections of created cases, module path by default")
+	flag.String("ASSIGNEE", "10", "testrail user id or email results are assigned to")
+	flag.String("VERSION", "1", "version of results: literal, git for git describe or env:NAME")
+	flag.String("STATUSES", "", "space separated testrail statuses of test statuses STATUS=ID or STATUS=NAME, ex.: \"SKIP=skipped N/A=7\"")
+	flag.Bool("CLOSE-RUN", false, "close run after results are uploaded")
+	flag.Bool("FORCE-CLOSE", false, "close run even if it has untested cases")
+	flag.Int("RETRIES", testrail.DefaultRetryPolicy.MaxAttempts, "max attempts of failed testrail request")
+	flag.Duration("RETRY-DEADLINE", testrail.DefaultRetryPolicy.Deadline, "max time spent on testrail request with retries")
+	flag.Int("BATCH-SIZE", testrail.DefaultBatchSize, "number of results sent in one request")
+	flag.Int("CONCURRENCY", testrail.DefaultConcurrency, "number of parallel upload requests")
+	flag.String("RUN-NAME", "{branch} {commit} {date}", "created run name template, supports {branch}, {commit} and {date}")
 	flag.Bool("SKIP-DESC", false, "skip description check")
-	flag.String("FORMAT", "json", "test output format")
-	flag.String("MATCHER", "default", "test output matcher")
+	flag.String("FORMAT", "json", "test output format json/text/junit/convlog")
+	flag.String("MATCHER", "default", "test output matcher default/logfmt, logfmt reads assured-ledger testrail log lines without titles")
+	flag.String("CASE-MARKERS", "default", "space separated case markers, builtin default/structured or NAME:REGEX with named groups id and title")
+	flag.String("MAPPING", "", "mapping file written by scan command, used for tests which logged no case")
+	flag.String("ISSUE-PATTERNS", "insolar", "space separated issue patterns of skipped tests, builtin name or NAME:REGEX[=>TEMPLATE]")
+	flag.Bool("DRY-RUN", false, "print results instead of uploading them to testrail")
+	flag.String("CASES", "", "testrail cases json export, used instead of testrail in dry run")
+	flag.String("DRY-RUN-OUTPUT", "table", "dry run output format table/json")
+	flag.Int("COMMENT-SIZE", testrail.DefaultCommentSize, "max size of test output attached as result comment, 0 disables it")
+	flag.String("FAIL-ON", "", "comma or space separated quality gates: not-found,wrong-desc,skip-no-issue,failed-tests")
+	flag.Int("MAX-NOT-FOUND", -1, "number of tests not found in testrail allowed by quality gate")
+	flag.Int("MAX-WRONG-DESC", -1, "number of tests with wrong title allowed by quality gate")
+	flag.Int("MAX-SKIP-NO-ISSUE", -1, "number of skipped tests without issue allowed by quality gate")
+	flag.Int("MAX-FAILED-TESTS", -1, "number of failed tests allowed by quality gate")
+	flag.String("REPORT", "", "file to write summary report to")
+	flag.String("REPORT-FORMAT", "json", "summary report format json/markdown/junit, audit supports json/markdown")
+	flag.String("TO", "testrail", "sync-titles direction: testrail updates case titles, source prints patch of go source")
+	flag.Bool("YES", false, "update case titles without confirmation")
+	flag.String("SPOOL-DIR", "", "directory results are saved to when testrail is unreachable, see replay command")
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	config.NormalizeFlags(pflag.CommandLine)
 	pflag.Parse()
 	viper.BindPFlags(pflag.CommandLine)
+	cfg, err := config.Setup(viper.GetViper(), pflag.CommandLine, viper.GetString("CONFIG-FILE"), viper.GetString("PROFILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	var (
-		url      = viper.GetString("URL")
-		user     = viper.GetString("USER")
-		pass     = viper.GetString("PASSWORD")
-		runID    = viper.GetInt("RUN_ID")
-		file     = viper.GetString("FILE")
-		skipDesc = viper.GetBool("SKIP-DESC")
-	)
-
-	if url == "" {
-		log.Fatal("provide TestRail url")
+	switch command := pflag.Arg(0); command {
+	case "", "upload":
+		os.Exit(upload())
+	case "validate":
+		os.Exit(validate())
+	case "report":
+		os.Exit(report())
+	case "convert":
+		convert()
+	case "replay":
+		replay()
+	case "scan":
+		scan()
+	case "sync-titles":
+		syncTitles()
+	case "audit":
+		audit()
+	case "config":
+		showConfig(cfg)
+	default:
+		log.Fatalf("Unsupported command %s", command)
 	}
-	if runID == 0 {
-		log.Fatal("provide run id, ex.: --RUN_ID=54, or env TR_RUN_ID=54")
+}
+
+// convertTests parses test output from file or stdin with --FORMAT parser and finds cases
+// logged by tests with --MATCHER converter
+func convertTests(file string) []*types.TestMatcher {
+	parserInstance, err := internal.LookupParser(viper.GetString("FORMAT"))
+	if err != nil {
+		log.Fatal(err)
 	}
-	if user == "" {
-		log.Fatal("provide user for TestRail authentication")
+	converter := converterInfo()
+
+	issues, err := issue.ParseList(strings.Fields(viper.GetString("ISSUE-PATTERNS")))
+	if err != nil {
+		log.Fatal(err)
 	}
-	if pass == "" {
-		log.Fatal("provide password/token for TestRail authentication")
+	opts := internal.ConverterOptions{
+		MaxOutputSize: viper.GetInt("COMMENT-SIZE"),
+		Issues:        issues,
+		Markers:       caseMarkers(),
 	}
-
-	var (
-		parserName = viper.GetString("format")
-		parserInstance parser.Parser
-	)
-	switch parserName {
-	case "json":
-		parserInstance = json.Parser{}
-	case "text":
-		parserInstance = text.Parser{}
-	default:
-		log.Fatalf("Unsupported format %s", parserName)
+	if viper.GetString("MAPPING") != "" {
+		opts.Mapping = loadMapping().Cases()
 	}
-
-	matcherInstance := internal.Converter{}
+	matcherInstance := converter.New(opts)
 
 	var stream io.Reader = os.Stdin
 	if file != "" {
@@ -84,14 +146,293 @@ func main() {
 		stream = f
 	}
 	eventReader := parserInstance.GetParseIterator(stream)
-	tObjects := matcherInstance.ConvertEventsToMatcherObjects(eventReader)
+	return matcherInstance.ConvertEventsToMatcherObjects(eventReader)
+}
 
+func converterInfo() internal.ConverterInfo {
+	converter, err := internal.LookupConverter(viper.GetString("MATCHER"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return converter
+}
+
+// skipDesc tells whether test titles aren't checked, converter may not find them
+func skipDesc() bool {
+	return viper.GetBool("SKIP-DESC") || !converterInfo().Titles
+}
+
+func caseMarkers() marker.Set {
+	markers, err := marker.ParseList(strings.Fields(viper.GetString("CASE-MARKERS")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return markers
+}
+
+// loadMapping reads --MAPPING file written by scan command
+func loadMapping() internal.Mapping {
+	f, err := os.Open(viper.GetString("MAPPING"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := internal.LoadMapping(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return m
+}
+
+// setMode sets which run cases without test result are marked N/A, cases of scoped mode are
+// in --SCOPE-SECTIONS or claimed by tests of --SCOPE-PACKAGES in input and mapping
+func setMode(t *testrail.Uploader, tObjects []*types.TestMatcher) {
+	mode, err := testrail.ParseMode(viper.GetString("MODE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	t.SetMode(mode)
+	if mode != testrail.ModeScoped {
+		return
+	}
+
+	var sections []int
+	for _, field := range strings.Fields(viper.GetString("SCOPE-SECTIONS")) {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			log.Fatalf("invalid scope section id %s", field)
+		}
+		sections = append(sections, id)
+	}
+	packages := strings.Fields(viper.GetString("SCOPE-PACKAGES"))
+	if len(sections) == 0 && len(packages) == 0 {
+		log.Fatal("provide sections or packages owned by job for scoped mode, ex.: --SCOPE-SECTIONS=\"140 141\" --SCOPE-PACKAGES=example.com/module/parser/...")
+	}
+
+	var mapping internal.Mapping
+	if len(packages) > 0 && viper.GetString("MAPPING") != "" {
+		mapping = loadMapping()
+	}
+	if err := t.SetScope(sections, internal.ScopeCases(packages, mapping, tObjects)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newUploader(url, user, pass string) *testrail.Uploader {
 	t := testrail.NewUploader(url, user, pass)
-	t.Init(runID)
+	retryPolicy := testrail.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = viper.GetInt("RETRIES")
+	retryPolicy.Deadline = viper.GetDuration("RETRY-DEADLINE")
+	t.SetRetryPolicy(retryPolicy)
+	t.SetBatching(viper.GetInt("BATCH-SIZE"), viper.GetInt("CONCURRENCY"))
+	t.SetCommentSize(viper.GetInt("COMMENT-SIZE"))
+	return t
+}
+
+// resultSettings sets version of results, assignee and statuses given by ids, names are looked up
+// once run is initialized, so results are spooled if testrail is unavailable, dry run takes ids only
+func resultSettings(t *testrail.Uploader, dryRun bool) testrail.ResultSettings {
+	statuses, err := testrail.ParseStatuses(viper.GetString("STATUSES"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	version, err := internal.ResolveVersion(viper.GetString("VERSION"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	t.SetVersion(version)
+
+	settings := testrail.ResultSettings{Assignee: viper.GetString("ASSIGNEE"), Statuses: statuses}
+	unresolved := t.SetResultIDs(settings)
+	if dryRun && unresolved != nil {
+		if unresolved.Assignee != "" {
+			log.Fatalf("assignee %s can't be looked up in dry run, provide user id", unresolved.Assignee)
+		}
+		for status, value := range unresolved.Statuses {
+			log.Fatalf("status %s=%s can't be looked up in dry run, provide status id", status, value)
+		}
+	}
+	return settings
+}
+
+// upload sends results to testrail and returns exit code of failed quality gate
+func upload() int {
+	var (
+		url      = viper.GetString("URL")
+		user     = viper.GetString("USER")
+		pass     = viper.GetString("PASSWORD")
+		runID    = viper.GetInt("RUN_ID")
+		planID   = viper.GetInt("PLAN_ID")
+		project  = viper.GetInt("PROJECT_ID")
+		suite    = viper.GetInt("SUITE_ID")
+		file     = viper.GetString("FILE")
+		dryRun   = viper.GetBool("DRY-RUN")
+		cases    = viper.GetString("CASES")
+		spoolDir = viper.GetString("SPOOL-DIR")
+	)
+
+	if dryRun {
+		if cases == "" {
+			log.Fatal("provide testrail cases json export for dry run, ex.: --CASES=cases.json")
+		}
+	} else {
+		if url == "" {
+			log.Fatal("provide TestRail url")
+		}
+		if runID == 0 && planID == 0 && (project == 0 || suite == 0) {
+			log.Fatal("provide run id, ex.: --RUN_ID=54, or env TR_RUN_ID=54, " +
+				"or plan id and configuration, ex.: --PLAN_ID=60 --CONFIG=\"Postgres, Linux\", " +
+				"or project and suite ids to create new run, ex.: --PROJECT_ID=3 --SUITE_ID=12")
+		}
+		if user == "" {
+			log.Fatal("provide user for TestRail authentication")
+		}
+		if pass == "" {
+			log.Fatal("provide password/token for TestRail authentication")
+		}
+	}
+
+	gates := parseGates()
+	tObjects := convertTests(file)
+
+	t := newUploader(url, user, pass)
+	settings := resultSettings(t, dryRun)
+	if dryRun {
+		f, err := os.Open(cases)
+		if err != nil {
+			log.Fatal(err)
+		}
+		caseList, err := testrail.LoadCases(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		t.InitWithCases(runID, caseList)
+	} else if runID != 0 {
+		if err := t.Init(runID); err != nil {
+			return spoolUnchecked(t, spoolDir, url, testrail.SpoolRun{RunID: runID}, tObjects, err)
+		}
+	} else if planID != 0 {
+		config := viper.GetString("CONFIG")
+		run, err := t.InitPlan(planID, config, suite)
+		if err != nil {
+			return spoolUnchecked(t, spoolDir, url, testrail.SpoolRun{PlanID: planID, Config: config, SuiteID: suite}, tObjects, err)
+		}
+		fmt.Printf("Using plan run %d (%s): %s\n", run.ID, run.Config, run.URL)
+	} else {
+		newRun := trlib.SendableRun{
+			SuiteID:     suite,
+			Name:        internal.FormatRunName(viper.GetString("RUN-NAME"), time.Now()),
+			MilestoneID: viper.GetInt("MILESTONE_ID"),
+			CaseIDs:     internal.CaseIDs(tObjects),
+		}
+		run, err := t.CreateRun(project, newRun)
+		if err != nil {
+			return spoolUnchecked(t, spoolDir, url, testrail.SpoolRun{
+				ProjectID: project, SuiteID: suite, Name: newRun.Name, MilestoneID: newRun.MilestoneID,
+			}, tObjects, err)
+		}
+		fmt.Printf("Created run %d: %s\n", run.ID, run.URL)
+	}
+	if !dryRun {
+		if err := t.ResolveResults(settings); err != nil {
+			return spoolUnchecked(t, spoolDir, url, testrail.SpoolRun{RunID: t.RunID()}, tObjects, err)
+		}
+	}
 
-	filteredObjects := internal.FilterTestObjects(tObjects, t.GetCasesWithDescription(), skipDesc)
+	setMode(t, tObjects)
+	filteredObjects := internal.FilterTestObjects(tObjects, t.GetCasesWithDescription(), skipDesc())
 	filteredObjects.LogInvalidTests(t)
+	exitCode := checkGates(filteredObjects, gates)
+	if viper.GetBool("CREATE-MISSING") {
+		createMissing(t, filteredObjects.NotFound, dryRun)
+	}
+	if path := viper.GetString("REPORT"); path != "" {
+		writeReport(path, internal.NewReport(filteredObjects, t, t.RunID(), t.RunURL()))
+	}
 
 	t.AddTests(filteredObjects.Valid, true)
-	t.Upload()
+	if dryRun {
+		if err := internal.PrintPayload(os.Stdout, runID, t.Payload(), viper.GetString("DRY-RUN-OUTPUT"), t.StatusName); err != nil {
+			log.Fatal(err)
+		}
+		return exitCode
+	}
+	if err := t.Upload(); err != nil {
+		if !testrail.CanReplay(err) || spoolDir == "" {
+			log.Fatal(err)
+		}
+		spool(spoolDir, url, testrail.SpoolRun{RunID: t.RunID()}, t, true, err)
+		return exitCode
+	}
+
+	if viper.GetBool("CLOSE-RUN") {
+		closed, err := t.Close(viper.GetBool("FORCE-CLOSE"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		// untested cases are expected in partial and scoped modes, upload itself succeeded
+		if !closed {
+			log.Printf("run %d has %d untested cases and is left open, use --FORCE-CLOSE to close it anyway",
+				t.RunID(), t.UntestedCount())
+		}
+	}
+	return exitCode
+}
+
+// parseGates returns quality gates enabled by --FAIL-ON and --MAX-* limits
+func parseGates() []internal.Gate {
+	gates, err := internal.ParseGates(viper.GetString("FAIL-ON"), map[string]int{
+		internal.GateNotFound:    viper.GetInt("MAX-NOT-FOUND"),
+		internal.GateWrongDesc:   viper.GetInt("MAX-WRONG-DESC"),
+		internal.GateSkipNoIssue: viper.GetInt("MAX-SKIP-NO-ISSUE"),
+		internal.GateFailedTests: viper.GetInt("MAX-FAILED-TESTS"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return gates
+}
+
+// checkGates logs failed quality gates and returns exit code of the first one
+func checkGates(summary *internal.TestObjectSummary, gates []internal.Gate) int {
+	violations := summary.CheckGates(gates)
+	for _, v := range violations {
+		log.Println(v.Error())
+	}
+	if len(violations) == 0 {
+		return 0
+	}
+	return violations[0].ExitCode()
+}
+
+// reportWriter is report or audit written in format given by --REPORT-FORMAT
+type reportWriter interface {
+	Write(w io.Writer, format string) error
+}
+
+func writeReport(path string, report reportWriter) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = report.Write(f, viper.GetString("REPORT-FORMAT"))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
+
+func withCaseID(objects []*types.TestMatcher) []*types.TestMatcher {
+	var filtered []*types.TestMatcher
+	for _, object := range objects {
+		if object.ID != 0 {
+			filtered = append(filtered, object)
+		}
+	}
+	return filtered
 }
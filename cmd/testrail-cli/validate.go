@@ -0,0 +1,49 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/cmd/testrail-cli/internal"
+	"github.com/insolar/testrail-cli/testrail"
+)
+
+// checkTests finds tests of input in cases of run, project suite or cases json export
+func checkTests() (*internal.TestObjectSummary, *testrail.Uploader) {
+	t := initSuiteCases()
+	tObjects := convertTests(viper.GetString("FILE"))
+	return internal.FilterTestObjects(tObjects, t.GetCasesWithDescription(), skipDesc()), t
+}
+
+// validate logs tests which aren't mapped to testrail cases properly without uploading results
+// and returns exit code of failed quality gate
+func validate() int {
+	gates := parseGates()
+	summary, t := checkTests()
+	summary.LogInvalidTests(t)
+	log.Printf("%d valid tests, %d not found, %d with wrong title, %d skipped without issue",
+		len(summary.Valid), len(summary.NotFound), len(summary.WrongDesc), len(summary.SkippedNoIssue))
+	return checkGates(summary, gates)
+}
+
+// report writes summary of tests to --REPORT file or stdout without uploading results
+// and returns exit code of failed quality gate
+func report() int {
+	gates := parseGates()
+	summary, t := checkTests()
+
+	r := internal.NewReport(summary, t, t.RunID(), t.RunURL())
+	if path := viper.GetString("REPORT"); path != "" {
+		writeReport(path, r)
+	} else if err := r.Write(os.Stdout, viper.GetString("REPORT-FORMAT")); err != nil {
+		log.Fatal(err)
+	}
+	return checkGates(summary, gates)
+}
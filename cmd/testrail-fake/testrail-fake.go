@@ -0,0 +1,56 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/insolar/testrail-cli/testrail/fake"
+)
+
+func main() {
+	viper.AutomaticEnv()
+	viper.SetEnvPrefix("TR")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	flag.String("ADDR", "127.0.0.1:8080", "address to listen on")
+	flag.String("STATE", "", "json file with cases, runs and plans, changes are saved to it")
+	flag.String("USER", "", "username checked if set")
+	flag.String("PASSWORD", "", "password checked if set")
+	flag.String("FAULTS", "", "space separated faults [ENDPOINT=][applied-]KIND[*TIMES], kind is http status, timeout or malformed, applied fault breaks response of handled request, ex.: add_results_for_cases=429*2")
+	flag.Duration("TIMEOUT", fake.DefaultTimeout, "how long server hangs on timeout fault")
+	flag.Int("PAGE-SIZE", 0, "respond to list endpoints with pages of size like testrail 6.7+, 0 responds with plain arrays")
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	pflag.Parse()
+	viper.BindPFlags(pflag.CommandLine)
+
+	server := fake.New(fake.State{})
+	if path := viper.GetString("STATE"); path != "" {
+		var err error
+		if server, err = fake.Load(path); err != nil {
+			log.Fatal(err)
+		}
+	}
+	server.User = viper.GetString("USER")
+	server.Password = viper.GetString("PASSWORD")
+	server.Timeout = viper.GetDuration("TIMEOUT")
+	server.PageSize = viper.GetInt("PAGE-SIZE")
+
+	faults, err := fake.ParseFaults(viper.GetString("FAULTS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.Inject(faults...)
+
+	addr := viper.GetString("ADDR")
+	log.Printf("fake testrail is listening on http://%s/", addr)
+	log.Fatal(http.ListenAndServe(addr, server))
+}
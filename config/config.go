@@ -0,0 +1,270 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+// Package config reads named profiles of cli settings from config file,
+// setting is resolved in order: flag, env, profile, default
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// FileName is config file looked up in current and home directories
+const FileName = ".testrail-cli.yaml"
+
+// Sources of effective setting value
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceProfile = "profile"
+	SourceDefault = "default"
+)
+
+const masked = "******"
+
+// Profile is set of settings keyed by normalized flag name
+type Profile map[string]string
+
+// File is config file, Default profile is used when profile isn't chosen
+type File struct {
+	Default  string
+	Profiles map[string]Profile
+}
+
+// Config is profile chosen from config file, it is empty without config file
+type Config struct {
+	Path     string
+	Profile  string
+	Settings Profile
+}
+
+// Setting is effective value of flag and where it comes from
+type Setting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Normalize returns setting key for flag name, so RUN_ID, run_id and run-id are the same setting
+func Normalize(name string) string {
+	return strings.Replace(strings.ToLower(name), "_", "-", -1)
+}
+
+// NormalizeFlags makes flags accept any spelling of their names, so --run-id, --run_id and
+// --RUN_ID are the same flag, it is called when all flags are defined
+func NormalizeFlags(flags *pflag.FlagSet) {
+	names := make(map[string]string)
+	flags.VisitAll(func(f *pflag.Flag) {
+		names[Normalize(f.Name)] = f.Name
+	})
+	flags.SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if flagName, ok := names[Normalize(name)]; ok {
+			return pflag.NormalizedName(flagName)
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// IsSecret reports whether setting value shouldn't be shown
+func IsSecret(name string) bool {
+	name = Normalize(name)
+	for _, word := range []string{"password", "token", "secret"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns path of config file in current or home directory, it is empty if there is none
+func Find() string {
+	dirs := []string{"."}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads config file, lists of setting are joined with spaces and maps are
+// written as space separated KEY=VALUE pairs, ex.: statuses
+func Load(path string) (File, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return File{}, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	f := File{Default: v.GetString("profile"), Profiles: make(map[string]Profile)}
+	for name, settings := range v.GetStringMap("profiles") {
+		values, ok := settings.(map[string]interface{})
+		if !ok {
+			return File{}, fmt.Errorf("profile %s of config %s isn't a map of settings", name, path)
+		}
+		p := make(Profile, len(values))
+		for key, value := range values {
+			p[Normalize(key)] = format(value)
+		}
+		f.Profiles[name] = p
+	}
+	return f, nil
+}
+
+func format(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, format(item))
+		}
+		return strings.Join(items, " ")
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(value))
+		for key, item := range value {
+			pairs = append(pairs, key+"="+format(item))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, " ")
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = item
+		}
+		return format(converted)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Select returns name and settings of profile, default profile is used if name is empty
+func (f File) Select(name string) (string, Profile, error) {
+	if name == "" {
+		name = f.Default
+	}
+	if name == "" {
+		return "", Profile{}, nil
+	}
+	p, ok := f.Profiles[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(f.Profiles))
+		for n := range f.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return "", nil, fmt.Errorf("unknown profile %s, available: %s", name, strings.Join(names, ", "))
+	}
+	return name, p, nil
+}
+
+// Setup reads config file, found in current or home directory if path is empty, and applies
+// settings of profile to v as config layer, so flags and env take precedence
+func Setup(v *viper.Viper, flags *pflag.FlagSet, path, profile string) (Config, error) {
+	if path == "" {
+		path = Find()
+	}
+	if path == "" {
+		if profile != "" {
+			return Config{}, fmt.Errorf("profile %s is chosen, but there is no %s", profile, FileName)
+		}
+		return Config{}, nil
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		return Config{}, err
+	}
+	name, settings, err := f.Select(profile)
+	if err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	flagNames := make(map[string]string)
+	flags.VisitAll(func(f *pflag.Flag) {
+		flagNames[Normalize(f.Name)] = f.Name
+	})
+	values := make(map[string]interface{}, len(settings))
+	var unsupported []string
+	for key, value := range settings {
+		flagName, ok := flagNames[key]
+		if !ok {
+			unsupported = append(unsupported, key)
+			continue
+		}
+		values[flagName] = value
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return Config{}, fmt.Errorf("config %s: unsupported settings of profile %s: %s", path, name, strings.Join(unsupported, ", "))
+	}
+	if err := v.MergeConfigMap(values); err != nil {
+		return Config{}, fmt.Errorf("failed to apply profile %s: %w", name, err)
+	}
+	return Config{Path: path, Profile: name, Settings: settings}, nil
+}
+
+// EnvKeyReplacer maps flag name to env var name, ex.: DRY-RUN is read from TR_DRY_RUN,
+// viper gets it with SetEnvKeyReplacer
+var EnvKeyReplacer = strings.NewReplacer("-", "_")
+
+// EnvName returns env var of flag, ENVPREFIX_NAME with dashes replaced by underscores
+func EnvName(envPrefix, name string) string {
+	return strings.ToUpper(envPrefix + "_" + EnvKeyReplacer.Replace(name))
+}
+
+// Resolve returns effective settings of flags, env var of flag is given by EnvName
+func (c Config) Resolve(v *viper.Viper, flags *pflag.FlagSet, envPrefix string) []Setting {
+	var settings []Setting
+	flags.VisitAll(func(f *pflag.Flag) {
+		s := Setting{Name: f.Name, Value: v.GetString(f.Name), Source: SourceDefault}
+		_, inProfile := c.Settings[Normalize(f.Name)]
+		switch {
+		case f.Changed:
+			s.Source = SourceFlag
+		case os.Getenv(EnvName(envPrefix, f.Name)) != "":
+			s.Source = SourceEnv
+		case inProfile:
+			s.Source = SourceProfile
+		}
+		if IsSecret(f.Name) && s.Value != "" {
+			s.Value = masked
+		}
+		settings = append(settings, s)
+	})
+	return settings
+}
+
+// Write prints config file, profile and effective settings in table, secrets are masked
+func (c Config) Write(w io.Writer, settings []Setting) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if c.Path == "" {
+		fmt.Fprintf(tw, "Config: none, %s not found\n", FileName)
+	} else if c.Profile == "" {
+		fmt.Fprintf(tw, "Config: %s, no profile chosen\n", c.Path)
+	} else {
+		fmt.Fprintf(tw, "Config: %s, profile: %s\n", c.Path, c.Profile)
+	}
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Value, s.Source)
+	}
+	return tw.Flush()
+}
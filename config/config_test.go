@@ -0,0 +1,151 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+profile: staging
+profiles:
+  staging:
+    url: https://staging.testrail.io/
+    user: ci@example.com
+    password: secret
+    run_id: 57
+    issue-patterns: [insolar, github]
+    statuses:
+      SKIP: skipped
+      N/A: 7
+  production:
+    URL: https://example.testrail.io/
+    RUN-ID: 12
+`
+
+func writeConfig(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	path := filepath.Join(dir, FileName)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoad(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	f, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", f.Default)
+	assert.Equal(t, Profile{
+		"url":            "https://staging.testrail.io/",
+		"user":           "ci@example.com",
+		"password":       "secret",
+		"run-id":         "57",
+		"issue-patterns": "insolar github",
+		"statuses":       "n/a=7 skip=skipped",
+	}, f.Profiles["staging"])
+
+	name, p, err := f.Select("")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", name)
+	assert.Equal(t, "57", p["run-id"])
+
+	_, p, err = f.Select("Production")
+	require.NoError(t, err)
+	assert.Equal(t, "12", p["run-id"])
+
+	_, _, err = f.Select("qa")
+	assert.EqualError(t, err, "unknown profile qa, available: production, staging")
+}
+
+func newFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("URL", "", "")
+	flags.String("USER", "", "")
+	flags.String("PASSWORD", "", "")
+	flags.Int("RUN_ID", 0, "")
+	flags.String("ISSUE-PATTERNS", "insolar", "")
+	flags.String("STATUSES", "", "")
+	flags.String("MODE", "partial", "")
+	return flags
+}
+
+func TestSetup(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	flags := newFlags()
+	require.NoError(t, flags.Parse([]string{"--USER=me@example.com"}))
+	require.NoError(t, os.Setenv("TRTEST_RUN_ID", "60"))
+	defer os.Unsetenv("TRTEST_RUN_ID")
+	require.NoError(t, os.Setenv("TRTEST_ISSUE_PATTERNS", "github"))
+	defer os.Unsetenv("TRTEST_ISSUE_PATTERNS")
+
+	v := viper.New()
+	v.SetEnvPrefix("TRTEST")
+	v.SetEnvKeyReplacer(EnvKeyReplacer)
+	v.AutomaticEnv()
+	require.NoError(t, v.BindPFlags(flags))
+
+	c, err := Setup(v, flags, path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", c.Profile)
+
+	assert.Equal(t, "me@example.com", v.GetString("USER"))
+	assert.Equal(t, 60, v.GetInt("RUN_ID"))
+	assert.Equal(t, "https://staging.testrail.io/", v.GetString("URL"))
+	assert.Equal(t, "partial", v.GetString("MODE"))
+
+	settings := make(map[string]Setting)
+	for _, s := range c.Resolve(v, flags, "TRTEST") {
+		settings[s.Name] = s
+	}
+	assert.Equal(t, Setting{Name: "USER", Value: "me@example.com", Source: SourceFlag}, settings["USER"])
+	assert.Equal(t, Setting{Name: "RUN_ID", Value: "60", Source: SourceEnv}, settings["RUN_ID"])
+	assert.Equal(t, Setting{Name: "ISSUE-PATTERNS", Value: "github", Source: SourceEnv}, settings["ISSUE-PATTERNS"])
+	assert.Equal(t, Setting{Name: "PASSWORD", Value: masked, Source: SourceProfile}, settings["PASSWORD"])
+	assert.Equal(t, Setting{Name: "STATUSES", Value: "n/a=7 skip=skipped", Source: SourceProfile}, settings["STATUSES"])
+	assert.Equal(t, Setting{Name: "MODE", Value: "partial", Source: SourceDefault}, settings["MODE"])
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf, c.Resolve(v, flags, "TRTEST")))
+	assert.NotContains(t, buf.String(), "secret")
+	assert.Contains(t, buf.String(), "profile: staging")
+}
+
+func TestNormalizeFlags(t *testing.T) {
+	flags := newFlags()
+	NormalizeFlags(flags)
+	require.NoError(t, flags.Parse([]string{"--run-id", "54", "--issue_patterns=github", "--url=https://example.testrail.io/"}))
+
+	runID, err := flags.GetInt("RUN_ID")
+	require.NoError(t, err)
+	assert.Equal(t, 54, runID)
+	assert.Equal(t, "github", flags.Lookup("ISSUE-PATTERNS").Value.String())
+	assert.True(t, flags.Lookup("URL").Changed)
+}
+
+func TestSetup_Errors(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig+"  broken:\n    run_idd: 1\n")
+	defer cleanup()
+
+	_, err := Setup(viper.New(), newFlags(), path, "broken")
+	assert.EqualError(t, err, "config "+path+": unsupported settings of profile broken: run-idd")
+
+	_, err = Setup(viper.New(), newFlags(), filepath.Join(filepath.Dir(path), "missing.yaml"), "")
+	assert.Error(t, err)
+}
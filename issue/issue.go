@@ -0,0 +1,122 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+// Package issue extracts issue tracker references from test output,
+// they are sent to testrail as result defects
+package issue
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pattern finds issue reference and formats it with template, ex.: "$1" or "${repo}#${num}"
+type Pattern struct {
+	Name     string
+	Re       *regexp.Regexp
+	Template string
+}
+
+// Find returns defect built from the first match of pattern
+func (p Pattern) Find(text string) (string, bool) {
+	submatches := p.Re.FindStringSubmatchIndex(text)
+	if submatches == nil {
+		return "", false
+	}
+	return string(p.Re.ExpandString(nil, p.Template, text, submatches)), true
+}
+
+func builtin(name, re, template string) Pattern {
+	return Pattern{Name: name, Re: regexp.MustCompile(re), Template: template}
+}
+
+// Builtin patterns could be referenced by name
+var Builtin = map[string]Pattern{
+	"insolar": builtin("insolar", `insolar\.atlassian\.net/browse/([A-Z]+-\d+)`, "$1"),
+	"jira":    builtin("jira", `https?://[^\s/]+/browse/([A-Z][A-Z0-9_]*-\d+)`, "$1"),
+	// https://github.com/org/repo/issues/123 or org/repo#123
+	"github": builtin("github",
+		`(?:https?://github\.com/([\w.-]+)/([\w.-]+)/issues/|\b([\w.-]+)/([\w.-]+)#)(\d+)`, "$1$3/$2$4#$5"),
+	"gitlab":   builtin("gitlab", `https?://[^\s/]+/([\w.-]+(?:/[\w.-]+)+)/-/issues/(\d+)`, "$1#$2"),
+	"youtrack": builtin("youtrack", `https?://[^\s/]+(?:/youtrack)?/issue/([A-Z][A-Z0-9_]*-\d+)`, "$1"),
+}
+
+// Matcher tries patterns in order
+type Matcher []Pattern
+
+// Default matcher keeps behaviour of insolar jira links
+var Default = Matcher{Builtin["insolar"]}
+
+// Find returns defect of the first matching pattern
+func (m Matcher) Find(text string) (string, bool) {
+	for _, p := range m {
+		if defect, ok := p.Find(text); ok {
+			return defect, true
+		}
+	}
+	return "", false
+}
+
+// BuiltinNames returns sorted names of builtin patterns
+func BuiltinNames() []string {
+	names := make([]string, 0, len(Builtin))
+	for name := range Builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse parses pattern spec, which is builtin pattern name or NAME:REGEX[=>TEMPLATE],
+// template defaults to the first capture group or the whole match if regex has no groups
+func Parse(spec string) (Pattern, error) {
+	if p, ok := Builtin[spec]; ok {
+		return p, nil
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Pattern{}, fmt.Errorf("issue pattern %q is neither builtin (%s) nor NAME:REGEX[=>TEMPLATE]",
+			spec, strings.Join(BuiltinNames(), ", "))
+	}
+
+	expr, template := parts[1], ""
+	if i := strings.LastIndex(expr, "=>"); i >= 0 {
+		expr, template = expr[:i], expr[i+2:]
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("issue pattern %s: %w", parts[0], err)
+	}
+	if template == "" {
+		template = "$0"
+		if re.NumSubexp() > 0 {
+			template = "${1}"
+		}
+	}
+	return Pattern{Name: parts[0], Re: re, Template: template}, nil
+}
+
+// ParseList parses pattern specs, Default is returned for empty list
+func ParseList(specs []string) (Matcher, error) {
+	var m Matcher
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		p, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, p)
+	}
+	if len(m) == 0 {
+		return Default, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,63 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package issue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		defect  string
+	}{
+		{"insolar", "skip: https://insolar.atlassian.net/browse/TASK-1 flaky", "TASK-1"},
+		{"jira", "https://example.atlassian.net/browse/PLAT-42", "PLAT-42"},
+		{"github", "https://github.com/insolar/testrail-cli/issues/12", "insolar/testrail-cli#12"},
+		{"github", "see insolar/testrail-cli#7 for details", "insolar/testrail-cli#7"},
+		{"gitlab", "https://gitlab.com/group/sub/project/-/issues/5", "group/sub/project#5"},
+		{"youtrack", "https://example.myjetbrains.com/youtrack/issue/DEV-3", "DEV-3"},
+	}
+	for _, test := range tests {
+		defect, ok := Builtin[test.pattern].Find(test.text)
+		require.True(t, ok, test.text)
+		assert.Equal(t, test.defect, defect, test.text)
+	}
+
+	_, ok := Default.Find("https://example.atlassian.net/browse/PLAT-42")
+	assert.False(t, ok)
+}
+
+func TestParse(t *testing.T) {
+	m, err := ParseList([]string{
+		`tracker:https://bugs\.example\.com/show\?id=(?P<id>\d+)=>BUG-${id}`,
+		`plain:TKT-\d+`,
+		"github",
+	})
+	require.NoError(t, err)
+	require.Len(t, m, 3)
+
+	defect, ok := m.Find("https://bugs.example.com/show?id=17")
+	require.True(t, ok)
+	assert.Equal(t, "BUG-17", defect)
+
+	defect, ok = m.Find("blocked by TKT-9")
+	require.True(t, ok)
+	assert.Equal(t, "TKT-9", defect)
+
+	_, err = Parse("unknown")
+	assert.Error(t, err)
+	_, err = Parse("broken:(")
+	assert.Error(t, err)
+
+	m, err = ParseList(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Default, m)
+}
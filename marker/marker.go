@@ -0,0 +1,182 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+// Package marker finds testrail case references in test output
+package marker
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+// Marker is regex with named groups id and optional title
+type Marker struct {
+	Name string
+	Re   *regexp.Regexp
+}
+
+func builtin(name, re string) Marker {
+	return Marker{Name: name, Re: regexp.MustCompile(re)}
+}
+
+// Builtin markers could be referenced by name
+var Builtin = map[string]Marker{
+	// t.Log("C3605 Some testcase description")
+	"default": builtin("default", `\bC(?P<id>\d{1,8})\s(?P<title>.*)`),
+	// t.Log(`testrail:case=3605 title="Some testcase description"`)
+	"structured": builtin("structured", `\btestrail:case=(?P<id>\d{1,8})(?:\s+title="(?P<title>(?:[^"\\]|\\.)*)")?`),
+}
+
+// Match is case reference found in line
+type Match struct {
+	Marker string
+	Ref    types.CaseRef
+	Start  int
+	End    int
+}
+
+func subexpIndex(re *regexp.Regexp, name string) int {
+	for i, n := range re.SubexpNames() {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// cutTitle ends title, which takes the rest of match, before the next reference,
+// ex.: "C101 first C102 second" has two references, match end is cut as well
+func cutTitle(re *regexp.Regexp, line string, loc []int, titleIdx int) {
+	start, end := loc[2*titleIdx], loc[2*titleIdx+1]
+	next := re.FindStringIndex(line[start:end])
+	if next == nil {
+		return
+	}
+	cut := start + len(strings.TrimRight(line[start:start+next[0]], " \t"))
+	loc[2*titleIdx+1], loc[1] = cut, cut
+}
+
+func (m Marker) findAll(line string) []Match {
+	var (
+		matches  []Match
+		idIdx    = subexpIndex(m.Re, "id")
+		titleIdx = subexpIndex(m.Re, "title")
+	)
+	for pos := 0; pos < len(line); {
+		loc := m.Re.FindStringSubmatchIndex(line[pos:])
+		if loc == nil {
+			break
+		}
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += pos
+			}
+		}
+		if titleIdx > 0 && loc[2*titleIdx] >= 0 && loc[2*titleIdx+1] == loc[1] {
+			cutTitle(m.Re, line, loc, titleIdx)
+		}
+		// the next reference could start right after the match, empty match moves on
+		pos = loc[1]
+		if loc[1] == loc[0] {
+			pos++
+		}
+
+		if loc[2*idIdx] < 0 {
+			continue
+		}
+		// custom marker could capture something else than number
+		id, err := strconv.Atoi(line[loc[2*idIdx]:loc[2*idIdx+1]])
+		if err != nil || id == 0 {
+			continue
+		}
+
+		match := Match{Marker: m.Name, Ref: types.CaseRef{ID: id}, Start: loc[0], End: loc[1]}
+		if titleIdx > 0 && loc[2*titleIdx] >= 0 {
+			match.Ref.Description = line[loc[2*titleIdx]:loc[2*titleIdx+1]]
+			if m.Name == "structured" {
+				if unquoted, err := strconv.Unquote(`"` + match.Ref.Description + `"`); err == nil {
+					match.Ref.Description = unquoted
+				}
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// Set is list of markers applied to every line
+type Set []Marker
+
+// Default set keeps C3605 convention
+var Default = Set{Builtin["default"]}
+
+// Find returns case references found in line by all markers, ordered by position
+func (s Set) Find(line string) []Match {
+	var matches []Match
+	for _, m := range s {
+		matches = append(matches, m.findAll(line)...)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return matches
+}
+
+// BuiltinNames returns sorted names of builtin markers
+func BuiltinNames() []string {
+	names := make([]string, 0, len(Builtin))
+	for name := range Builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse parses marker spec, which is builtin marker name or NAME:REGEX,
+// regex must have named group id and could have named group title
+func Parse(spec string) (Marker, error) {
+	if m, ok := Builtin[spec]; ok {
+		return m, nil
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Marker{}, fmt.Errorf("case marker %q is neither builtin (%s) nor NAME:REGEX",
+			spec, strings.Join(BuiltinNames(), ", "))
+	}
+	re, err := regexp.Compile(parts[1])
+	if err != nil {
+		return Marker{}, fmt.Errorf("case marker %s: %w", parts[0], err)
+	}
+	if subexpIndex(re, "id") < 0 {
+		return Marker{}, fmt.Errorf("case marker %s has no named group id, ex.: (?P<id>\\d+)", parts[0])
+	}
+	return Marker{Name: parts[0], Re: re}, nil
+}
+
+// ParseList parses marker specs, Default is returned for empty list
+func ParseList(specs []string) (Set, error) {
+	var s Set
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		m, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, m)
+	}
+	if len(s) == 0 {
+		return Default, nil
+	}
+	return s, nil
+}
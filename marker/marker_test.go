@@ -0,0 +1,70 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package marker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+func refs(matches []Match) []types.CaseRef {
+	var list []types.CaseRef
+	for _, m := range matches {
+		list = append(list, m.Ref)
+	}
+	return list
+}
+
+func TestSet_Find(t *testing.T) {
+	set, err := ParseList([]string{"default", "structured", `ticket:\[TR-(?P<id>\d+)\]`})
+	require.NoError(t, err)
+
+	tests := []struct {
+		line string
+		refs []types.CaseRef
+	}{
+		{"    example_test.go:16: C9999 Pass test\n", []types.CaseRef{{ID: 9999, Description: "Pass test"}}},
+		{"TC123 is not a case", nil},
+		{`testrail:case=12 title="Login with \"admin\" user"`, []types.CaseRef{{ID: 12, Description: `Login with "admin" user`}}},
+		{"testrail:case=13", []types.CaseRef{{ID: 13}}},
+		{"[TR-7] and [TR-8]", []types.CaseRef{{ID: 7}, {ID: 8}}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.refs, refs(set.Find(test.line)), test.line)
+	}
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse("unknown")
+	assert.Error(t, err)
+	_, err = Parse(`noid:C(\d+)`)
+	assert.Error(t, err)
+	_, err = Parse(`broken:(?P<id>`)
+	assert.Error(t, err)
+
+	set, err := ParseList(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Default, set)
+}
+
+func TestDefault_Find(t *testing.T) {
+	tests := []struct {
+		line string
+		refs []types.CaseRef
+	}{
+		{"C101 first", []types.CaseRef{{ID: 101, Description: "first"}}},
+		{"C101 first C102 second", []types.CaseRef{{ID: 101, Description: "first"}, {ID: 102, Description: "second"}}},
+		{"C101 C102 second", []types.CaseRef{{ID: 101, Description: ""}, {ID: 102, Description: "second"}}},
+		{"C101 fixed in ABC102 build", []types.CaseRef{{ID: 101, Description: "fixed in ABC102 build"}}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.refs, refs(Default.Find(test.line)), test.line)
+	}
+}
@@ -0,0 +1,43 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package junit
+
+import "github.com/insolar/testrail-cli/parser"
+
+var expectedLog = []parser.TestEvent{
+	{Action: "run", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample", Elapsed: 0.000000, Output: ""},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample", Elapsed: 0.000000, Output: "=== RUN   TestExample\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample", Elapsed: 0.000000, Output: "    example_test.go:16: C9999 Pass test\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample", Elapsed: 0.000000, Output: "--- PASS: TestExample (0.10s)\n"},
+	{Action: "pass", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample", Elapsed: 0.100000, Output: ""},
+	{Action: "run", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample2", Elapsed: 0.000000, Output: ""},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample2", Elapsed: 0.000000, Output: "=== RUN   TestExample2\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample2", Elapsed: 0.000000, Output: "Failed\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample2", Elapsed: 0.000000, Output: "    example_test.go:22: C3606 Fail testsdf\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample2", Elapsed: 0.000000, Output: "--- FAIL: TestExample2 (0.30s)\n"},
+	{Action: "fail", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample2", Elapsed: 0.300000, Output: ""},
+	{Action: "run", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample3", Elapsed: 0.000000, Output: ""},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample3", Elapsed: 0.000000, Output: "=== RUN   TestExample3\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample3", Elapsed: 0.000000, Output: "    example_test.go:28: C3607 Skip test\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample3", Elapsed: 0.000000, Output: "https://insolar.atlassian.net/browse/TASK-1 not ready\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample3", Elapsed: 0.000000, Output: "--- SKIP: TestExample3 (0.50s)\n"},
+	{Action: "skip", Package: "github.com/insolar/testrail-cli/package1", Test: "TestExample3", Elapsed: 0.500000, Output: ""},
+	{Action: "run", Package: "github.com/insolar/testrail-cli/package1", Test: "TestMGRGroupCreateCheckEmptySequence/groupGoal=200", Elapsed: 0.000000, Output: ""},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestMGRGroupCreateCheckEmptySequence/groupGoal=200", Elapsed: 0.000000, Output: "=== RUN   TestMGRGroupCreateCheckEmptySequence/groupGoal=200\n"},
+	{Action: "output", Package: "github.com/insolar/testrail-cli/package1", Test: "TestMGRGroupCreateCheckEmptySequence/groupGoal=200", Elapsed: 0.000000, Output: "--- PASS: TestMGRGroupCreateCheckEmptySequence/groupGoal=200 (0.00s)\n"},
+	{Action: "pass", Package: "github.com/insolar/testrail-cli/package1", Test: "TestMGRGroupCreateCheckEmptySequence/groupGoal=200", Elapsed: 0.000000, Output: ""},
+	{Action: "run", Package: "login.spec.js", Test: "C3703 Login with valid password", Elapsed: 0.000000, Output: ""},
+	{Action: "output", Package: "login.spec.js", Test: "C3703 Login with valid password", Elapsed: 0.000000, Output: "=== RUN   C3703 Login with valid password\n"},
+	{Action: "output", Package: "login.spec.js", Test: "C3703 Login with valid password", Elapsed: 0.000000, Output: "--- PASS: C3703 Login with valid password (0.31s)\n"},
+	{Action: "pass", Package: "login.spec.js", Test: "C3703 Login with valid password", Elapsed: 0.312000, Output: ""},
+	{Action: "run", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 0.000000, Output: ""},
+	{Action: "output", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 0.000000, Output: "=== RUN   Login C3704 Login with invalid password\n"},
+	{Action: "output", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 0.000000, Output: "Timeout\n"},
+	{Action: "output", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 0.000000, Output: "Error: timeout of 1000000ms exceeded\n"},
+	{Action: "output", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 0.000000, Output: "    at Context.<anonymous> (login.spec.js:21:5)\n"},
+	{Action: "output", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 0.000000, Output: "--- FAIL: Login C3704 Login with invalid password (1000.02s)\n"},
+	{Action: "fail", Package: "login.spec.js", Test: "Login C3704 Login with invalid password", Elapsed: 1000.017000, Output: ""},
+}
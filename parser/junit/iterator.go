@@ -0,0 +1,153 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/insolar/testrail-cli/parser"
+)
+
+type testSuite struct {
+	Name string `xml:"name,attr"`
+}
+
+type testResult struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type testCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	Time      string       `xml:"time,attr"`
+	Failures  []testResult `xml:"failure"`
+	Errors    []testResult `xml:"error"`
+	Skipped   *testResult  `xml:"skipped"`
+	SystemOut string       `xml:"system-out"`
+	SystemErr string       `xml:"system-err"`
+}
+
+type iterativeReader struct {
+	decoder *xml.Decoder
+	suites  []testSuite
+	buffer  []parser.TestEvent
+}
+
+func (i *iterativeReader) popBuffer() (string, parser.TestEvent, error) {
+	te := i.buffer[0]
+	i.buffer = i.buffer[1:]
+	return parser.UniqueTestKeyFromEvent(te), te, nil
+}
+
+func (i *iterativeReader) Next() (string, parser.TestEvent, error) {
+	for len(i.buffer) == 0 {
+		token, err := i.decoder.Token()
+		if err == io.EOF {
+			return "", parser.TestEvent{}, io.EOF
+		} else if err != nil {
+			return "", parser.TestEvent{}, fmt.Errorf("failed to read junit xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "testsuite":
+				suite := testSuite{}
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "name" {
+						suite.Name = attr.Value
+					}
+				}
+				i.suites = append(i.suites, suite)
+			case "testcase":
+				var tc testCase
+				if err := i.decoder.DecodeElement(&tc, &t); err != nil {
+					return "", parser.TestEvent{}, fmt.Errorf("failed to decode junit testcase: %w", err)
+				}
+				i.buffer = append(i.buffer, i.convertTestCase(tc)...)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "testsuite" && len(i.suites) > 0 {
+				i.suites = i.suites[:len(i.suites)-1]
+			}
+		}
+	}
+
+	return i.popBuffer()
+}
+
+// convertTestCase emits events in the same order as go test2json does for single test,
+// status line is printed as go test does, so converters could rely on it
+func (i *iterativeReader) convertTestCase(tc testCase) []parser.TestEvent {
+	// classname is package for go and module for pytest, but jest duplicates test name there
+	pkg := tc.ClassName
+	if (pkg == "" || pkg == tc.Name) && len(i.suites) > 0 {
+		pkg = i.suites[len(i.suites)-1].Name
+	}
+
+	// time could be formatted with thousands separator, ex.: 1,234.567
+	elapsed, _ := strconv.ParseFloat(strings.Replace(tc.Time, ",", "", -1), 64)
+
+	action, status := "pass", "PASS"
+	switch {
+	case len(tc.Failures) > 0 || len(tc.Errors) > 0:
+		action, status = "fail", "FAIL"
+	case tc.Skipped != nil:
+		action, status = "skip", "SKIP"
+	}
+
+	events := []parser.TestEvent{
+		{Action: "run", Package: pkg, Test: tc.Name},
+		{Action: "output", Package: pkg, Test: tc.Name, Output: "=== RUN   " + tc.Name + "\n"},
+	}
+	output := func(text string) {
+		for _, line := range splitLines(text) {
+			events = append(events, parser.TestEvent{Action: "output", Package: pkg, Test: tc.Name, Output: line})
+		}
+	}
+
+	output(tc.SystemOut)
+	output(tc.SystemErr)
+	for _, r := range append(tc.Failures, tc.Errors...) {
+		output(r.Message)
+		output(r.Text)
+	}
+	if tc.Skipped != nil {
+		output(tc.Skipped.Message)
+		output(tc.Skipped.Text)
+	}
+
+	events = append(events,
+		parser.TestEvent{
+			Action:  "output",
+			Package: pkg,
+			Test:    tc.Name,
+			Output:  fmt.Sprintf("--- %s: %s (%.2fs)\n", status, tc.Name, elapsed),
+		},
+		parser.TestEvent{Action: action, Package: pkg, Test: tc.Name, Elapsed: elapsed},
+	)
+
+	return events
+}
+
+func splitLines(text string) []string {
+	text = strings.Trim(text, "\r\n")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r") + "\n"
+	}
+	return lines
+}
@@ -0,0 +1,25 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package junit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Parse(t *testing.T) {
+	parser := Parser{}
+
+	f, err := os.Open("example_test.xml")
+	require.NoError(t, err)
+
+	res := parser.Parse(f)
+
+	assert.Equal(t, expectedLog, res)
+}
@@ -0,0 +1,445 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/educlos/testrail"
+)
+
+// RetryPolicy describes how failed testrail requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is number of tries for one request, including the first one
+	MaxAttempts int
+	// BaseDelay is delay after first failure, it is doubled on every next one
+	BaseDelay time.Duration
+	// MaxDelay caps delay between attempts
+	MaxDelay time.Duration
+	// Deadline limits time spent on one request with all its retries, zero means no limit
+	Deadline time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Deadline:    2 * time.Minute,
+}
+
+// APIError is unsuccessful testrail response
+type APIError struct {
+	Method     string
+	URI        string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("testrail %s %s: status %d: %s", e.Method, e.URI, e.StatusCode, e.Body)
+}
+
+// Temporary reports whether request could succeed if retried
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// AmbiguousError is failure of request changing testrail, which could be applied anyway,
+// ex.: timeout after request was sent, such request isn't retried, as retry could apply it twice
+type AmbiguousError struct {
+	Method string
+	URI    string
+	Err    error
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("testrail %s %s could be applied: %v", e.Method, e.URI, e.Err)
+}
+
+func (e *AmbiguousError) Unwrap() error {
+	return e.Err
+}
+
+// IsAmbiguous reports whether failed request could be applied by testrail
+func IsAmbiguous(err error) bool {
+	var uploadErr *UploadError
+	if errors.As(err, &uploadErr) {
+		return uploadErr.Ambiguous()
+	}
+
+	var ambiguousErr *AmbiguousError
+	return errors.As(err, &ambiguousErr)
+}
+
+// notDelivered reports whether failed request provably didn't reach testrail, so request
+// changing testrail is safe to retry: testrail throttled or is unavailable, or connection failed
+func notDelivered(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable
+	}
+
+	// connection refused and unresolved host fail on dial, before request is written
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// IsTemporary reports whether failed request could succeed later: testrail is overloaded,
+// unavailable or unreachable, ambiguous failures are not temporary
+func IsTemporary(err error) bool {
+	var uploadErr *UploadError
+	if errors.As(err, &uploadErr) {
+		return uploadErr.Temporary()
+	}
+
+	var ambiguousErr *AmbiguousError
+	if errors.As(err, &ambiguousErr) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+
+	// url.Error is net.Error as well, so it goes first
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// connection refused/reset, timeouts and connection closed by server
+		_, isNetErr := urlErr.Err.(net.Error)
+		return isNetErr || urlErr.Err == io.EOF || urlErr.Err == io.ErrUnexpectedEOF
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// parseRetryAfter parses Retry-After header, which is either seconds or http date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// apiClient sends requests to testrail api v2, it mirrors methods of testrail.Client,
+// but exposes response status and retries temporary failures
+type apiClient struct {
+	url        string
+	username   string
+	password   string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+func newAPIClient(baseURL, username, password string) *apiClient {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &apiClient{
+		url:        baseURL + "index.php?/api/v2/",
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: time.Minute},
+		retry:      DefaultRetryPolicy,
+	}
+}
+
+// backoff returns delay before next attempt: exponential with jitter or the one asked by server
+func (c *apiClient) backoff(attempt int, err error) time.Duration {
+	if e, ok := err.(*APIError); ok && e.RetryAfter > 0 {
+		return e.RetryAfter
+	}
+
+	delay := c.retry.BaseDelay
+	for i := 1; i < attempt && delay < c.retry.MaxDelay; i++ {
+		delay *= 2
+	}
+	if c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// half of delay is random, so parallel clients don't retry simultaneously
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (c *apiClient) sendRequest(method, uri string, data, v interface{}) error {
+	var body []byte
+	if data != nil {
+		var err error
+		if body, err = json.Marshal(data); err != nil {
+			return fmt.Errorf("failed to marshal %s request: %w", uri, err)
+		}
+	}
+
+	started := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := c.send(method, uri, body, v)
+		if err == nil || !IsTemporary(err) {
+			return err
+		}
+		// POST changes testrail, it is retried only if it didn't reach testrail
+		if method != http.MethodGet && !notDelivered(err) {
+			return &AmbiguousError{Method: method, URI: uri, Err: err}
+		}
+		if attempt >= c.retry.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := c.backoff(attempt, err)
+		if c.retry.Deadline > 0 && time.Since(started)+delay > c.retry.Deadline {
+			return fmt.Errorf("giving up after %d attempts, deadline %s exceeded: %w", attempt, c.retry.Deadline, err)
+		}
+
+		log.Printf("testrail request failed, retry in %s: %v", delay, err)
+		time.Sleep(delay)
+	}
+}
+
+func (c *apiClient) send(method, uri string, body []byte, v interface{}) error {
+	req, err := http.NewRequest(method, c.url+uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", uri, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &APIError{
+			Method:     method,
+			URI:        uri,
+			StatusCode: resp.StatusCode,
+			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(respBody, v); err != nil {
+			return fmt.Errorf("failed to unmarshal %s response: %w", uri, err)
+		}
+	}
+	return nil
+}
+
+func (c *apiClient) GetRun(runID int) (testrail.Run, error) {
+	run := testrail.Run{}
+	err := c.sendRequest("GET", "get_run/"+strconv.Itoa(runID), nil, &run)
+	return run, err
+}
+
+func (c *apiClient) AddRun(projectID int, newRun testrail.SendableRun) (testrail.Run, error) {
+	run := testrail.Run{}
+	err := c.sendRequest("POST", "add_run/"+strconv.Itoa(projectID), newRun, &run)
+	return run, err
+}
+
+func (c *apiClient) CloseRun(runID int) (testrail.Run, error) {
+	run := testrail.Run{}
+	err := c.sendRequest("POST", "close_run/"+strconv.Itoa(runID), nil, &run)
+	return run, err
+}
+
+// getPages requests all pages of list endpoint, testrail 6.7+ responds with page, which has items
+// under key and link to the next page, older versions respond with plain array of all items
+func (c *apiClient) getPages(uri, key string, add func(items json.RawMessage) error) error {
+	for uri != "" {
+		var resp json.RawMessage
+		if err := c.sendRequest("GET", uri, nil, &resp); err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(resp), []byte("{")) {
+			if err := add(resp); err != nil {
+				return fmt.Errorf("failed to unmarshal %s response: %w", uri, err)
+			}
+			return nil
+		}
+
+		var page map[string]json.RawMessage
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return fmt.Errorf("failed to unmarshal %s response: %w", uri, err)
+		}
+		items, ok := page[key]
+		if !ok {
+			return fmt.Errorf("%s response has no %s", uri, key)
+		}
+		if err := add(items); err != nil {
+			return fmt.Errorf("failed to unmarshal %s response: %w", uri, err)
+		}
+
+		var links struct {
+			Next string `json:"next"`
+		}
+		if data, ok := page["_links"]; ok {
+			if err := json.Unmarshal(data, &links); err != nil {
+				return fmt.Errorf("failed to unmarshal %s response links: %w", uri, err)
+			}
+		}
+		// link is relative to api, ex.: /api/v2/get_cases/1&suite_id=2&limit=250&offset=250
+		uri = strings.TrimPrefix(links.Next, "/api/v2/")
+	}
+	return nil
+}
+
+func (c *apiClient) GetCases(projectID, suiteID int) ([]testrail.Case, error) {
+	cases := []testrail.Case{}
+	err := c.getPages(fmt.Sprintf("get_cases/%d&suite_id=%d", projectID, suiteID), "cases", func(items json.RawMessage) error {
+		var page []testrail.Case
+		if err := json.Unmarshal(items, &page); err != nil {
+			return err
+		}
+		cases = append(cases, page...)
+		return nil
+	})
+	return cases, err
+}
+
+func (c *apiClient) AddCase(sectionID int, newCase testrail.SendableCase) (testrail.Case, error) {
+	created := testrail.Case{}
+	err := c.sendRequest("POST", "add_case/"+strconv.Itoa(sectionID), newCase, &created)
+	return created, err
+}
+
+func (c *apiClient) GetSections(projectID, suiteID int) ([]testrail.Section, error) {
+	sections := []testrail.Section{}
+	err := c.getPages(fmt.Sprintf("get_sections/%d&suite_id=%d", projectID, suiteID), "sections", func(items json.RawMessage) error {
+		var page []testrail.Section
+		if err := json.Unmarshal(items, &page); err != nil {
+			return err
+		}
+		sections = append(sections, page...)
+		return nil
+	})
+	return sections, err
+}
+
+func (c *apiClient) AddSection(projectID int, newSection testrail.SendableSection) (testrail.Section, error) {
+	section := testrail.Section{}
+	err := c.sendRequest("POST", "add_section/"+strconv.Itoa(projectID), newSection, &section)
+	return section, err
+}
+
+func (c *apiClient) UpdateCase(caseID int, updates testrail.SendableCase) (testrail.Case, error) {
+	updated := testrail.Case{}
+	err := c.sendRequest("POST", "update_case/"+strconv.Itoa(caseID), updates, &updated)
+	return updated, err
+}
+
+func (c *apiClient) GetStatuses() ([]testrail.Status, error) {
+	statuses := []testrail.Status{}
+	err := c.sendRequest("GET", "get_statuses", nil, &statuses)
+	return statuses, err
+}
+
+func (c *apiClient) GetUserByEmail(email string) (testrail.User, error) {
+	user := testrail.User{}
+	err := c.sendRequest("GET", "get_user_by_email&email="+url.QueryEscape(email), nil, &user)
+	return user, err
+}
+
+func (c *apiClient) AddResultsForCases(runID int, results testrail.SendableResultsForCase) ([]testrail.Result, error) {
+	created := []testrail.Result{}
+	err := c.sendRequest("POST", "add_results_for_cases/"+strconv.Itoa(runID), results, &created)
+	return created, err
+}
+
+// runTest is case added to run, results of run refer to tests
+type runTest struct {
+	ID     int `json:"id"`
+	CaseID int `json:"case_id"`
+}
+
+// runResult is result of run test, testrail.Result fails to unmarshal some of its fields
+type runResult struct {
+	ID        int   `json:"id"`
+	TestID    int   `json:"test_id"`
+	StatusID  int   `json:"status_id"`
+	CreatedOn int64 `json:"created_on"`
+}
+
+func (c *apiClient) GetTests(runID int) ([]runTest, error) {
+	tests := []runTest{}
+	err := c.getPages("get_tests/"+strconv.Itoa(runID), "tests", func(items json.RawMessage) error {
+		var page []runTest
+		if err := json.Unmarshal(items, &page); err != nil {
+			return err
+		}
+		tests = append(tests, page...)
+		return nil
+	})
+	return tests, err
+}
+
+// GetResultsForRun returns results added to run since createdAfter unix time
+func (c *apiClient) GetResultsForRun(runID int, createdAfter int64) ([]runResult, error) {
+	results := []runResult{}
+	uri := fmt.Sprintf("get_results_for_run/%d&created_after=%d", runID, createdAfter)
+	err := c.getPages(uri, "results", func(items json.RawMessage) error {
+		var page []runResult
+		if err := json.Unmarshal(items, &page); err != nil {
+			return err
+		}
+		results = append(results, page...)
+		return nil
+	})
+	return results, err
+}
+
+func (c *apiClient) GetPlan(planID int) (testrail.Plan, error) {
+	plan := testrail.Plan{}
+	err := c.sendRequest("GET", "get_plan/"+strconv.Itoa(planID), nil, &plan)
+	return plan, err
+}
+
+func (c *apiClient) AddPlanEntry(planID int, newEntry testrail.SendableEntry) (testrail.Entry, error) {
+	entry := testrail.Entry{}
+	err := c.sendRequest("POST", "add_plan_entry/"+strconv.Itoa(planID), newEntry, &entry)
+	return entry, err
+}
+
+func (c *apiClient) GetConfigs(projectID int) ([]testrail.Configuration, error) {
+	configs := []testrail.Configuration{}
+	err := c.sendRequest("GET", "get_configs/"+strconv.Itoa(projectID), nil, &configs)
+	return configs, err
+}
@@ -0,0 +1,164 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIClient(handler http.HandlerFunc) (*apiClient, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	c := newAPIClient(server.URL, "user", "password")
+	c.retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Deadline: time.Second}
+	return c, server
+}
+
+func TestAPIClient_Retry(t *testing.T) {
+	t.Run("temporary failures", func(t *testing.T) {
+		var calls int32
+		c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				_, _ = w.Write([]byte(`{"id": 54, "suite_id": 2}`))
+			}
+		})
+		defer server.Close()
+
+		run, err := c.GetRun(54)
+		require.NoError(t, err)
+		assert.Equal(t, 54, run.ID)
+		assert.EqualValues(t, 3, calls)
+	})
+
+	t.Run("permanent failure", func(t *testing.T) {
+		var calls int32
+		c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "Field :run_id is not a valid test run."}`))
+		})
+		defer server.Close()
+
+		_, err := c.GetRun(54)
+		require.Error(t, err)
+		apiErr, ok := err.(*APIError)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.EqualValues(t, 1, calls)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		var calls int32
+		c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		defer server.Close()
+
+		_, err := c.GetRun(54)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "giving up after 3 attempts")
+		assert.EqualValues(t, 3, calls)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		defer server.Close()
+
+		_, err := c.GetRun(54)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "deadline")
+	})
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 6, 17, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, time.Minute, parseRetryAfter("Wed, 17 Jun 2020 10:01:00 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Wed, 17 Jun 2020 09:59:00 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func TestAPIClient_GetCases(t *testing.T) {
+	pages := map[string]string{
+		"/api/v2/get_cases/1&suite_id=2": `{"offset": 0, "limit": 2, "size": 2,
+			"_links": {"next": "/api/v2/get_cases/1&suite_id=2&limit=2&offset=2", "prev": null},
+			"cases": [{"id": 1, "title": "first"}, {"id": 2, "title": "second"}]}`,
+		"/api/v2/get_cases/1&suite_id=2&limit=2&offset=2": `{"offset": 2, "limit": 2, "size": 1,
+			"_links": {"next": null, "prev": "/api/v2/get_cases/1&suite_id=2&limit=2&offset=0"},
+			"cases": [{"id": 3, "title": "third"}]}`,
+		"/api/v2/get_cases/1&suite_id=3": `[{"id": 4, "title": "plain"}]`,
+		"/api/v2/get_cases/1&suite_id=4": `{"offset": 0, "limit": 250, "size": 0, "_links": {"next": null}}`,
+	}
+	c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.RawQuery]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	})
+	defer server.Close()
+
+	cases, err := c.GetCases(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []testrail.Case{{ID: 1, Title: "first"}, {ID: 2, Title: "second"}, {ID: 3, Title: "third"}}, cases)
+
+	cases, err = c.GetCases(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []testrail.Case{{ID: 4, Title: "plain"}}, cases)
+
+	_, err = c.GetCases(1, 4)
+	assert.EqualError(t, err, "get_cases/1&suite_id=4 response has no cases")
+}
+
+func TestAPIClient_GetSections(t *testing.T) {
+	pages := map[string]string{
+		"/api/v2/get_sections/1&suite_id=2": `{"offset": 0, "limit": 1, "size": 1,
+			"_links": {"next": "/api/v2/get_sections/1&suite_id=2&limit=1&offset=1", "prev": null},
+			"sections": [{"id": 1, "name": "client"}]}`,
+		"/api/v2/get_sections/1&suite_id=2&limit=1&offset=1": `{"offset": 1, "limit": 1, "size": 1,
+			"_links": {"next": null, "prev": "/api/v2/get_sections/1&suite_id=2&limit=1&offset=0"},
+			"sections": [{"id": 2, "name": "server", "parent_id": 1}]}`,
+		"/api/v2/get_sections/1&suite_id=3": `[{"id": 3, "name": "plain"}]`,
+	}
+	c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.RawQuery]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	})
+	defer server.Close()
+
+	sections, err := c.GetSections(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []testrail.Section{{ID: 1, Name: "client"}, {ID: 2, Name: "server", ParentID: 1}}, sections)
+
+	sections, err = c.GetSections(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []testrail.Section{{ID: 3, Name: "plain"}}, sections)
+}
@@ -0,0 +1,40 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/educlos/testrail"
+)
+
+// LoadCases reads case list exported from TestRail as json, both plain get_cases
+// array and paginated {"cases": [...]} response are accepted
+func LoadCases(r io.Reader) ([]testrail.Case, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cases: %w", err)
+	}
+
+	var cases []testrail.Case
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var page struct {
+			Cases []testrail.Case `json:"cases"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cases: %w", err)
+		}
+		cases = page.Cases
+	} else if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cases: %w", err)
+	}
+
+	return cases, nil
+}
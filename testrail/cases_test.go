@@ -0,0 +1,52 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCases(t *testing.T) {
+	expected := []testrail.Case{{ID: 1, Title: "first"}, {ID: 2, Title: "second"}}
+	tests := []struct {
+		name  string
+		input string
+		cases []testrail.Case
+	}{
+		{
+			name:  "plain array",
+			input: `[{"id": 1, "title": "first"}, {"id": 2, "title": "second"}]`,
+			cases: expected,
+		},
+		{
+			name:  "paginated",
+			input: ` {"offset": 0, "limit": 250, "size": 2, "_links": {"next": null}, "cases": [{"id": 1, "title": "first"}, {"id": 2, "title": "second"}]}`,
+			cases: expected,
+		},
+		{
+			name:  "empty",
+			input: `[]`,
+			cases: []testrail.Case{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cases, err := LoadCases(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.cases, cases)
+		})
+	}
+
+	for _, input := range []string{`[{"id": "one"}]`, `{"cases": [`, `cases`} {
+		_, err := LoadCases(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+}
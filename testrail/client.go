@@ -6,10 +6,13 @@
 package testrail
 
 import (
+	"errors"
+	"fmt"
 	"log"
-	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/educlos/testrail"
@@ -19,7 +22,9 @@ import (
 )
 
 var (
+	// autotestUserID, resultVersion and statusMap are defaults of the instance cli is written for
 	autotestUserID = 10
+	resultVersion  = "1"
 	statusMap      = map[string]int{
 		types.TestStatusPassed:       testrail.StatusPassed,
 		types.TestStatusFailed:       testrail.StatusFailed,
@@ -28,109 +33,892 @@ var (
 	}
 )
 
+const (
+	DefaultBatchSize   = 500
+	DefaultConcurrency = 1
+	// DefaultCommentSize limits test output sent as result comment
+	DefaultCommentSize = 4096
+)
+
+// Mode tells which run cases without test result are sent as N/A
+type Mode string
+
+const (
+	// ModeFull marks N/A every run case, so cases which lost implementation are seen
+	ModeFull Mode = "full"
+	// ModePartial sends only results of tests found in input
+	ModePartial Mode = "partial"
+	// ModeScoped marks N/A only cases owned by job: in scope sections or claimed by scope packages
+	ModeScoped Mode = "scoped"
+)
 
-func TicketFromURL(url string) string {
-	if strings.HasPrefix(url, "https") || strings.HasPrefix(url, "http") {
-		s := strings.Split(url, "/")
-		return s[len(s)-1]
+// ParseMode checks upload mode name
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(name); mode {
+	case ModeFull, ModePartial, ModeScoped:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported mode %s, use full, partial or scoped", name)
 	}
-	return url
 }
 
 type Uploader struct {
-	c   *testrail.Client
+	c   *apiClient
 	run testrail.Run
 
 	runID        int
 	tests        map[int]testrail.SendableResult
 	defaultTests types.TestCasesWithDescription
+	// known are run cases, results of other cases are rejected by testrail
+	known map[int]types.TestCaseWithDescription
+
+	mode          Mode
+	scopeSections map[int]bool
+	scopeCases    map[int]bool
+
+	assigneeID int
+	version    string
+	statuses   map[string]int
+	// unresolved are settings given by names, which weren't looked up in testrail yet
+	unresolved *ResultSettings
+	// testStatuses are test statuses of results by case id, statuses are set to spooled results on replay
+	testStatuses map[int]string
+
+	commentSize int
+
+	batchSize   int
+	concurrency int
+	sent        map[int]bool
+	// ambiguous are cases of batches, which failed, but could be added to run anyway
+	ambiguous      map[int]bool
+	ambiguousSince time.Time
+	batchSent      func()
+
+	sections []testrail.Section
 }
 
 func NewUploader(url string, user string, password string) *Uploader {
 	return &Uploader{
-		c:     testrail.NewClient(url, user, password),
-		tests: make(map[int]testrail.SendableResult),
+		c:            newAPIClient(url, user, password),
+		tests:        make(map[int]testrail.SendableResult),
+		known:        make(map[int]types.TestCaseWithDescription),
+		mode:         ModePartial,
+		assigneeID:   autotestUserID,
+		version:      resultVersion,
+		statuses:     copyStatuses(statusMap),
+		testStatuses: make(map[int]string),
+		commentSize:  DefaultCommentSize,
+		batchSize:    DefaultBatchSize,
+		concurrency:  DefaultConcurrency,
+		sent:         make(map[int]bool),
+		ambiguous:    make(map[int]bool),
+	}
+}
+
+// SetBatching splits upload into batches of size results sent by concurrency parallel requests
+func (m *Uploader) SetBatching(size int, concurrency int) {
+	if size > 0 {
+		m.batchSize = size
 	}
+	if concurrency > 0 {
+		m.concurrency = concurrency
+	}
+}
+
+// SetCommentSize limits output of subtests sharing case, which is joined into one comment,
+// 0 disables comments
+func (m *Uploader) SetCommentSize(size int) {
+	m.commentSize = size
+}
+
+// SetRetryPolicy changes how failed testrail requests are retried, DefaultRetryPolicy is used otherwise
+func (m *Uploader) SetRetryPolicy(policy RetryPolicy) {
+	m.c.retry = policy
 }
 
 func (m Uploader) FormatURL(id int) string {
-	return path.Join(viper.GetString("URL"), "/index.php?/cases/view/", strconv.Itoa(id))
+	// path.Join would squash scheme slashes
+	return strings.TrimSuffix(viper.GetString("URL"), "/") + "/index.php?/cases/view/" + strconv.Itoa(id)
 }
 
-func (m *Uploader) getCasesWithDescription(projectID int, suiteID int) types.TestCasesWithDescription {
+func (m *Uploader) getCasesWithDescription(projectID int, suiteID int) (types.TestCasesWithDescription, error) {
 	cases, err := m.c.GetCases(projectID, suiteID)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to get cases of suite %d: %w", suiteID, err)
 	}
+	return casesWithDescription(cases), nil
+}
 
+func casesWithDescription(cases []testrail.Case) types.TestCasesWithDescription {
 	var casesWithDescription types.TestCasesWithDescription
 	for _, c := range cases {
 		caseWithDescription := types.TestCaseWithDescription{
 			ID:          c.ID,
 			Description: c.Title,
+			SectionID:   c.SectionID,
 		}
 		casesWithDescription = append(casesWithDescription, caseWithDescription)
 	}
 	return casesWithDescription
 }
 
-func (m *Uploader) Init(runID int) {
+func (m *Uploader) Init(runID int) error {
 	run, err := m.c.GetRun(runID)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to get run %d: %w", runID, err)
 	}
 	m.run = run
 
 	m.runID = runID
 
-	testCasesWithDescription := m.getCasesWithDescription(m.run.ProjectID, m.run.SuiteID)
+	testCasesWithDescription, err := m.getCasesWithDescription(m.run.ProjectID, m.run.SuiteID)
+	if err != nil {
+		return err
+	}
+	m.initTests(testCasesWithDescription)
+	return nil
+}
+
+// InitSuite prepares uploader to check tests against suite cases without run
+func (m *Uploader) InitSuite(projectID, suiteID int) error {
+	testCasesWithDescription, err := m.getCasesWithDescription(projectID, suiteID)
+	if err != nil {
+		return err
+	}
+	m.initTests(testCasesWithDescription)
+	return nil
+}
+
+// UpdateCaseTitle changes title of testrail case
+func (m *Uploader) UpdateCaseTitle(caseID int, title string) error {
+	if _, err := m.c.UpdateCase(caseID, testrail.SendableCase{Title: title}); err != nil {
+		return fmt.Errorf("failed to update case %d: %w", caseID, err)
+	}
+	return nil
+}
+
+// Suite returns project and suite of run results are uploaded to
+func (m Uploader) Suite() (projectID int, suiteID int) {
+	return m.run.ProjectID, m.run.SuiteID
+}
+
+func (m *Uploader) loadSections(projectID, suiteID int) error {
+	if m.sections != nil {
+		return nil
+	}
+	sections, err := m.c.GetSections(projectID, suiteID)
+	if err != nil {
+		return fmt.Errorf("failed to get sections of suite %d: %w", suiteID, err)
+	}
+	m.sections = sections
+	return nil
+}
+
+// SectionNames returns section paths by section id, ex.: "parser / json"
+func (m *Uploader) SectionNames(projectID, suiteID int) (map[int]string, error) {
+	if err := m.loadSections(projectID, suiteID); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]testrail.Section, len(m.sections))
+	for _, s := range m.sections {
+		byID[s.ID] = s
+	}
+	names := make(map[int]string, len(m.sections))
+	for _, s := range m.sections {
+		path := []string{s.Name}
+		// depth is limited in case of parent loop
+		for parent, ok := byID[s.ParentID]; ok && len(path) <= len(m.sections); parent, ok = byID[parent.ParentID] {
+			path = append([]string{parent.Name}, path...)
+		}
+		names[s.ID] = strings.Join(path, " / ")
+	}
+	return names, nil
+}
+
+// EnsureSection returns id of section found by path of names under parent section,
+// missing sections are added, zero parent is suite root
+func (m *Uploader) EnsureSection(projectID, suiteID, parentID int, path []string) (int, error) {
+	if err := m.loadSections(projectID, suiteID); err != nil {
+		return 0, err
+	}
+
+	for _, name := range path {
+		found := false
+		for _, s := range m.sections {
+			if s.ParentID == parentID && s.Name == name {
+				parentID, found = s.ID, true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		section, err := m.c.AddSection(projectID, testrail.SendableSection{SuiteID: suiteID, ParentID: parentID, Name: name})
+		if err != nil {
+			return 0, fmt.Errorf("failed to add section %s: %w", name, err)
+		}
+		// response could miss parent of new section
+		section.ParentID = parentID
+		m.sections = append(m.sections, section)
+		parentID = section.ID
+	}
+	return parentID, nil
+}
+
+// AddCase adds case to section
+func (m *Uploader) AddCase(sectionID int, title string) (testrail.Case, error) {
+	created, err := m.c.AddCase(sectionID, testrail.SendableCase{Title: title})
+	if err != nil {
+		return testrail.Case{}, fmt.Errorf("failed to add case %q: %w", title, err)
+	}
+	return created, nil
+}
+
+// CreateRun adds new run to the project and prepares uploader for it, run is limited to
+// newRun.CaseIDs, cases missing in suite are dropped, run without cases isn't created
+func (m *Uploader) CreateRun(projectID int, newRun testrail.SendableRun) (testrail.Run, error) {
+	if len(newRun.CaseIDs) == 0 {
+		return testrail.Run{}, errors.New("no case ids to create run with")
+	}
+
+	testCasesWithDescription, err := m.getCasesWithDescription(projectID, newRun.SuiteID)
+	if err != nil {
+		return testrail.Run{}, err
+	}
+
+	requested := make(map[int]bool)
+	for _, id := range newRun.CaseIDs {
+		requested[id] = true
+	}
+	included := make(map[int]bool)
+	newRun.CaseIDs = nil
 	for _, testCase := range testCasesWithDescription {
-		// update all cases with N/A status, we store all autotests in ONE run, so in case
-		// someone delete particular case implementation status must be updated to N/A
-		m.tests[testCase.ID] = testrail.SendableResult{
-			AssignedToID: autotestUserID,
-			StatusID:     statusMap[types.TestStatusNotAvailable],
-			Comment:      "",
-			Version:      "1",
-			Elapsed:      *testrail.TimespanFromDuration(1 * time.Second),
-			Defects:      "",
+		if requested[testCase.ID] {
+			newRun.CaseIDs = append(newRun.CaseIDs, testCase.ID)
+			included[testCase.ID] = true
+		}
+	}
+	if len(newRun.CaseIDs) == 0 {
+		return testrail.Run{}, fmt.Errorf("none of %d cases exists in suite %d", len(requested), newRun.SuiteID)
+	}
+	includeAll := false
+	newRun.IncludeAll = &includeAll
+
+	run, err := m.c.AddRun(projectID, newRun)
+	if err != nil {
+		return testrail.Run{}, fmt.Errorf("failed to add run: %w", err)
+	}
+	m.run = run
+
+	m.runID = run.ID
+
+	m.initTests(testCasesWithDescription)
+	// results for cases out of run are rejected by testrail
+	for caseID := range m.known {
+		if !included[caseID] {
+			delete(m.known, caseID)
+		}
+	}
+
+	return run, nil
+}
+
+// configNames splits plan run configuration, ex.: "Postgres, Linux", to normalized names
+func configNames(config string) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range strings.Split(config, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func sameConfig(a, b string) bool {
+	namesA, namesB := configNames(a), configNames(b)
+	if len(namesA) != len(namesB) {
+		return false
+	}
+	for name := range namesA {
+		if !namesB[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// InitPlan prepares uploader for run of plan entry with given configuration, ex.: "Postgres, Linux",
+// entry is added to plan if there is no such run yet, suiteID could be omitted if plan has only one suite
+func (m *Uploader) InitPlan(planID int, config string, suiteID int) (testrail.Run, error) {
+	plan, err := m.c.GetPlan(planID)
+	if err != nil {
+		return testrail.Run{}, fmt.Errorf("failed to get plan %d: %w", planID, err)
+	}
+
+	suites := make(map[int]bool)
+	for _, entry := range plan.Entries {
+		if suiteID != 0 && entry.SuiteID != suiteID {
+			continue
+		}
+		suites[entry.SuiteID] = true
+
+		for _, run := range entry.Runs {
+			if sameConfig(run.Config, config) {
+				err := m.Init(run.ID)
+				return m.run, err
+			}
+		}
+	}
+
+	if suiteID == 0 {
+		if len(suites) != 1 {
+			return testrail.Run{}, fmt.Errorf("can't choose suite for new entry of plan %d, provide suite id", planID)
+		}
+		for id := range suites {
+			suiteID = id
 		}
 	}
+
+	configIDs, err := m.getConfigIDs(plan.ProjectID, config)
+	if err != nil {
+		return testrail.Run{}, err
+	}
+
+	entry, err := m.c.AddPlanEntry(planID, testrail.SendableEntry{
+		SuiteID:    suiteID,
+		IncludeAll: true,
+		ConfigIDs:  configIDs,
+	})
+	if err != nil {
+		return testrail.Run{}, fmt.Errorf("failed to add entry to plan %d: %w", planID, err)
+	}
+
+	for _, run := range entry.Runs {
+		if sameConfig(run.Config, config) || len(entry.Runs) == 1 {
+			err := m.Init(run.ID)
+			return m.run, err
+		}
+	}
+	return testrail.Run{}, fmt.Errorf("plan %d entry %s has no run with configuration %q", planID, entry.ID, config)
+}
+
+func (m *Uploader) getConfigIDs(projectID int, config string) ([]int, error) {
+	configurations, err := m.c.GetConfigs(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configurations of project %d: %w", projectID, err)
+	}
+
+	var ids []int
+	for name := range configNames(config) {
+		found := false
+		for _, group := range configurations {
+			for _, c := range group.Configs {
+				if strings.ToLower(c.Name) == name {
+					ids = append(ids, c.ID)
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown configuration %q in project %d", name, projectID)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// ResolveRun finds run of entry spooled before its run was known: run of plan entry is used or
+// new run is created, entry must be saved with resolved run before its results are sent
+func (m *Uploader) ResolveRun(entry *SpoolEntry) error {
+	if entry.RunID != 0 {
+		return nil
+	}
+	if entry.RunAmbiguous {
+		return fmt.Errorf("%s could be created by failed request, set run_id of entry if it is", entry.SpoolRun)
+	}
+
+	var (
+		run testrail.Run
+		err error
+	)
+	if entry.PlanID != 0 {
+		run, err = m.InitPlan(entry.PlanID, entry.Config, entry.SuiteID)
+	} else {
+		caseIDs := make([]int, 0, len(entry.Payload.Results))
+		for _, result := range entry.Payload.Results {
+			caseIDs = append(caseIDs, result.CaseID)
+		}
+		run, err = m.CreateRun(entry.ProjectID, testrail.SendableRun{
+			SuiteID:     entry.SuiteID,
+			Name:        entry.Name,
+			MilestoneID: entry.MilestoneID,
+			CaseIDs:     caseIDs,
+		})
+	}
+	if err != nil {
+		if IsAmbiguous(err) {
+			entry.RunAmbiguous = true
+		}
+		return err
+	}
+	entry.RunID = run.ID
+	return nil
+}
+
+// InitSpooled prepares uploader to send spooled results, results which weren't validated
+// are checked against run cases, as testrail rejects whole request having unknown case,
+// ambiguous results are dropped if run has results of their cases added since they were sent
+func (m *Uploader) InitSpooled(entry SpoolEntry) error {
+	m.runID = entry.RunID
+	// spooled payload already has N/A results of its mode
+	m.mode = ModePartial
+	for _, result := range entry.Payload.Results {
+		m.tests[result.CaseID] = result.SendableResult
+	}
+
+	if !entry.Validated {
+		run, err := m.c.GetRun(entry.RunID)
+		if err != nil {
+			return fmt.Errorf("failed to get run %d: %w", entry.RunID, err)
+		}
+		m.run = run
+
+		testCasesWithDescription, err := m.getCasesWithDescription(run.ProjectID, run.SuiteID)
+		if err != nil {
+			return err
+		}
+		known := make(map[int]bool)
+		for _, testCase := range testCasesWithDescription {
+			known[testCase.ID] = true
+		}
+		for caseID := range m.tests {
+			if !known[caseID] {
+				delete(m.tests, caseID)
+			}
+		}
+		m.defaultTests = testCasesWithDescription
+	}
+
+	if entry.Unresolved != nil {
+		if err := m.resolveSpooled(*entry.Unresolved); err != nil {
+			return err
+		}
+	}
+	if entry.Ambiguous != nil {
+		return m.dropDelivered(*entry.Ambiguous)
+	}
+	return nil
+}
+
+// dropDelivered removes ambiguous results of cases, which have results added to run since
+// they were sent, such results are considered delivered
+func (m *Uploader) dropDelivered(ambiguous Ambiguity) error {
+	results, err := m.c.GetResultsForRun(m.runID, ambiguous.Since)
+	if err != nil {
+		return fmt.Errorf("failed to check results of run %d: %w", m.runID, err)
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	tests, err := m.c.GetTests(m.runID)
+	if err != nil {
+		return fmt.Errorf("failed to check results of run %d: %w", m.runID, err)
+	}
+
+	testCases := make(map[int]int, len(tests))
+	for _, test := range tests {
+		testCases[test.ID] = test.CaseID
+	}
+	added := make(map[int]bool)
+	for _, result := range results {
+		added[testCases[result.TestID]] = true
+	}
+
+	dropped := 0
+	for _, caseID := range ambiguous.CaseIDs {
+		if _, ok := m.tests[caseID]; ok && added[caseID] {
+			delete(m.tests, caseID)
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		log.Printf("%d of %d ambiguous results are already added to run %d, they are not sent again",
+			dropped, len(ambiguous.CaseIDs), m.runID)
+	}
+	return nil
+}
+
+// InitWithCases prepares uploader for run without requesting testrail,
+// suite cases are taken from cases list, ex.: loaded with LoadCases
+func (m *Uploader) InitWithCases(runID int, cases []testrail.Case) {
+	m.runID = runID
+
+	m.initTests(casesWithDescription(cases))
+}
+
+func (m *Uploader) initTests(testCasesWithDescription types.TestCasesWithDescription) {
+	for _, testCase := range testCasesWithDescription {
+		m.known[testCase.ID] = testCase
+	}
 	m.defaultTests = testCasesWithDescription
 }
 
+// SetMode sets which run cases without test result are sent as N/A, scoped mode needs SetScope
+func (m *Uploader) SetMode(mode Mode) {
+	m.mode = mode
+}
+
+// SetScope sets cases owned by job in scoped mode: cases of sections and their subsections
+// and cases claimed by job tests, subsections are known only for run fetched from testrail
+func (m *Uploader) SetScope(sectionIDs []int, caseIDs []int) error {
+	m.scopeSections = make(map[int]bool)
+	for _, id := range sectionIDs {
+		m.scopeSections[id] = true
+	}
+	m.scopeCases = make(map[int]bool)
+	for _, id := range caseIDs {
+		m.scopeCases[id] = true
+	}
+	if len(sectionIDs) == 0 || m.run.ProjectID == 0 {
+		return nil
+	}
+
+	if err := m.loadSections(m.run.ProjectID, m.run.SuiteID); err != nil {
+		return err
+	}
+	// sections are added until no more subsections are found
+	for added := true; added; {
+		added = false
+		for _, section := range m.sections {
+			if m.scopeSections[section.ParentID] && !m.scopeSections[section.ID] {
+				m.scopeSections[section.ID] = true
+				added = true
+			}
+		}
+	}
+	return nil
+}
+
+// notApplicable reports whether case without test result is sent as N/A, we store all
+// autotests in ONE run, so in case someone deletes particular case implementation
+// its status must be updated to N/A
+func (m Uploader) notApplicable(testCase types.TestCaseWithDescription) bool {
+	switch m.mode {
+	case ModeFull:
+		return true
+	case ModeScoped:
+		return m.scopeSections[testCase.SectionID] || m.scopeCases[testCase.ID]
+	default:
+		return false
+	}
+}
+
 func (m Uploader) GetCasesWithDescription() types.TestCasesWithDescription {
 	return m.defaultTests
 }
 
+// elapsedDuration converts test elapsed seconds to result duration, testrail keeps
+// elapsed time with one second resolution and doesn't accept zero timespan
+func elapsedDuration(seconds float64) time.Duration {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	if d < time.Second {
+		return time.Second
+	}
+	return d
+}
+
+// formatComment formats test output as testrail markdown code block
+func formatComment(output string) string {
+	if output == "" {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// statusSeverity orders statuses of subtests sharing case, failure of any subtest fails the case
+var statusSeverity = map[string]int{
+	types.TestStatusPassed:  1,
+	types.TestStatusSkipped: 2,
+	types.TestStatusFailed:  3,
+}
+
 func (m *Uploader) AddTests(objects []*types.TestMatcher, ignoreNonExistent bool) {
+	// subtests sharing case ID are reported as one result, so their time and output are joined
+	// and the worst status wins
+	var (
+		elapsed = make(map[int]float64)
+		output  = make(map[int]*OutputBuffer)
+		status  = make(map[int]string)
+	)
+
 	for _, object := range objects {
-		if _, ok := m.tests[object.ID]; !ok && ignoreNonExistent {
+		if _, ok := m.known[object.ID]; !ok && ignoreNonExistent {
 			continue
 		}
+		elapsed[object.ID] += object.Elapsed
+		if output[object.ID] == nil {
+			output[object.ID] = NewOutputBuffer(m.commentSize)
+		}
+		output[object.ID].AddOutput(object.Output)
+		if current, ok := status[object.ID]; !ok || statusSeverity[object.Status] > statusSeverity[current] {
+			status[object.ID] = object.Status
+		}
+		m.testStatuses[object.ID] = status[object.ID]
 		m.tests[object.ID] = testrail.SendableResult{
-			AssignedToID: autotestUserID,
-			StatusID:     statusMap[object.Status],
-			Comment:      "",
-			Version:      "1",
-			Elapsed:      *testrail.TimespanFromDuration(1 * time.Second),
-			Defects:      TicketFromURL(object.IssueURL),
+			AssignedToID: m.assigneeID,
+			StatusID:     m.statuses[status[object.ID]],
+			Comment:      formatComment(output[object.ID].String()),
+			Version:      m.version,
+			Elapsed:      *testrail.TimespanFromDuration(elapsedDuration(elapsed[object.ID])),
+			Defects:      object.IssueURL,
 		}
 	}
 }
 
-func (m *Uploader) Upload() {
+// RunID returns id of run results are uploaded to
+func (m Uploader) RunID() int {
+	return m.runID
+}
+
+// RunURL returns url of run results are uploaded to, it is empty in dry run
+func (m Uploader) RunURL() string {
+	return m.run.URL
+}
+
+// Payload returns results exactly as Upload sends them, ordered by case id
+func (m Uploader) Payload() testrail.SendableResultsForCase {
 	sendableResults := testrail.SendableResultsForCase{}
 
+	for caseID, testCase := range m.known {
+		if _, ok := m.tests[caseID]; ok || !m.notApplicable(testCase) {
+			continue
+		}
+		sendableResults.Results = append(sendableResults.Results, testrail.ResultsForCase{
+			CaseID: caseID,
+			SendableResult: testrail.SendableResult{
+				AssignedToID: m.assigneeID,
+				StatusID:     m.statuses[types.TestStatusNotAvailable],
+				Version:      m.version,
+				Elapsed:      *testrail.TimespanFromDuration(1 * time.Second),
+			},
+		})
+	}
 	for caseID, resultForCase := range m.tests {
 		sendableResults.Results = append(sendableResults.Results, testrail.ResultsForCase{
 			CaseID:         caseID,
 			SendableResult: resultForCase,
 		})
 	}
+	sort.Slice(sendableResults.Results, func(i, j int) bool {
+		return sendableResults.Results[i].CaseID < sendableResults.Results[j].CaseID
+	})
+
+	return sendableResults
+}
+
+// OnBatchSent sets callback called after every successfully uploaded batch
+func (m *Uploader) OnBatchSent(f func()) {
+	m.batchSent = f
+}
+
+// Pending returns results which are not delivered by Upload yet, ambiguous ones included
+func (m Uploader) Pending() testrail.SendableResultsForCase {
+	pending := testrail.SendableResultsForCase{}
+	for _, result := range m.Payload().Results {
+		if !m.sent[result.CaseID] {
+			pending.Results = append(pending.Results, result)
+		}
+	}
+	return pending
+}
+
+// Ambiguous returns pending results of batches, which failed, but could be added to run anyway,
+// it is nil if there are none, such results are not sent again by Upload
+func (m Uploader) Ambiguous() *Ambiguity {
+	if len(m.ambiguous) == 0 {
+		return nil
+	}
+	a := &Ambiguity{Since: m.ambiguousSince.Add(-clockSkew).Unix()}
+	for caseID := range m.ambiguous {
+		a.CaseIDs = append(a.CaseIDs, caseID)
+	}
+	sort.Ints(a.CaseIDs)
+	return a
+}
+
+// BatchError is failure of one upload batch
+type BatchError struct {
+	Batch       int
+	FirstCaseID int
+	LastCaseID  int
+	Err         error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("batch %d (cases C%d-C%d): %v", e.Batch, e.FirstCaseID, e.LastCaseID, e.Err)
+}
+
+// UploadError lists failed batches, their results stay pending and are sent by next Upload call
+type UploadError struct {
+	RunID   int
+	Batches []BatchError
+}
+
+func (e *UploadError) Error() string {
+	msgs := make([]string, 0, len(e.Batches))
+	for _, b := range e.Batches {
+		msgs = append(msgs, b.Error())
+	}
+	return fmt.Sprintf("failed to add results to run %d, %d batches failed: %s", e.RunID, len(e.Batches), strings.Join(msgs, "; "))
+}
+
+// Temporary reports whether all batches failed because testrail was unavailable
+func (e *UploadError) Temporary() bool {
+	for _, b := range e.Batches {
+		if !IsTemporary(b.Err) {
+			return false
+		}
+	}
+	return len(e.Batches) > 0
+}
 
-	_, err := m.c.AddResultsForCases(m.runID, sendableResults)
+// Ambiguous reports whether some batches could be added to run despite failure
+func (e *UploadError) Ambiguous() bool {
+	for _, b := range e.Batches {
+		if IsAmbiguous(b.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReplay reports whether failed upload could be spooled and replayed later: every batch failed
+// because testrail was unavailable or could be added to run anyway, the latter are checked on replay
+func CanReplay(err error) bool {
+	var uploadErr *UploadError
+	if !errors.As(err, &uploadErr) {
+		return IsTemporary(err)
+	}
+	for _, b := range uploadErr.Batches {
+		if !IsTemporary(b.Err) && !IsAmbiguous(b.Err) {
+			return false
+		}
+	}
+	return len(uploadErr.Batches) > 0
+}
+
+// Upload sends pending results in batches, results of successful batches are not sent again
+// if Upload is repeated after failure, neither are results of batches failed ambiguously
+func (m *Uploader) Upload() error {
+	var pending []testrail.ResultsForCase
+	for _, result := range m.Pending().Results {
+		if !m.ambiguous[result.CaseID] {
+			pending = append(pending, result)
+		}
+	}
+	started := time.Now()
+
+	var batches [][]testrail.ResultsForCase
+	for len(pending) > 0 {
+		size := m.batchSize
+		if size > len(pending) {
+			size = len(pending)
+		}
+		batches = append(batches, pending[:size])
+		pending = pending[size:]
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		uploadErr = &UploadError{RunID: m.runID}
+		sem       = make(chan struct{}, m.concurrency)
+		done      int
+	)
+	for i, batch := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(num int, batch []testrail.ResultsForCase) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			_, err := m.c.AddResultsForCases(m.runID, testrail.SendableResultsForCase{Results: batch})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if IsAmbiguous(err) {
+					for _, result := range batch {
+						m.ambiguous[result.CaseID] = true
+					}
+					if m.ambiguousSince.IsZero() {
+						m.ambiguousSince = started
+					}
+				}
+				uploadErr.Batches = append(uploadErr.Batches, BatchError{
+					Batch:       num,
+					FirstCaseID: batch[0].CaseID,
+					LastCaseID:  batch[len(batch)-1].CaseID,
+					Err:         err,
+				})
+				log.Printf("batch %d/%d failed: %v", num, len(batches), err)
+				return
+			}
+
+			for _, result := range batch {
+				m.sent[result.CaseID] = true
+			}
+			done += len(batch)
+			log.Printf("batch %d/%d uploaded, %d results sent", num, len(batches), done)
+			if m.batchSent != nil {
+				m.batchSent()
+			}
+		}(i+1, batch)
+	}
+	wg.Wait()
+
+	if len(uploadErr.Batches) > 0 {
+		sort.Slice(uploadErr.Batches, func(i, j int) bool {
+			return uploadErr.Batches[i].Batch < uploadErr.Batches[j].Batch
+		})
+		return uploadErr
+	}
+	return nil
+}
+
+// Close closes the run, run which still has untested cases is left open unless forced,
+// returns whether run is closed
+func (m *Uploader) Close(force bool) (bool, error) {
+	run, err := m.c.GetRun(m.runID)
 	if err != nil {
-		log.Fatal(err)
+		return false, fmt.Errorf("failed to get run %d: %w", m.runID, err)
 	}
-}
\ No newline at end of file
+	m.run = run
+
+	if run.IsCompleted {
+		return true, nil
+	}
+	if run.UntestedCount > 0 && !force {
+		return false, nil
+	}
+
+	if _, err := m.c.CloseRun(m.runID); err != nil {
+		return false, fmt.Errorf("failed to close run %d: %w", m.runID, err)
+	}
+	return true, nil
+}
+
+// UntestedCount returns number of untested cases in run as of last request
+func (m Uploader) UntestedCount() int {
+	return m.run.UntestedCount
+}
@@ -0,0 +1,188 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/educlos/testrail"
+	"github.com/insolar/testrail-cli/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploader_Upload(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received [][]int
+		failing  = true
+	)
+	c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		var results testrail.SendableResultsForCase
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&results)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var ids []int
+		for _, result := range results.Results {
+			ids = append(ids, result.CaseID)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, ids)
+		if failing && ids[0] == 3 {
+			failing = false
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	})
+	defer server.Close()
+
+	m := NewUploader(server.URL, "user", "password")
+	m.c = c
+	m.SetBatching(2, 2)
+	m.SetMode(ModeFull)
+	m.InitWithCases(54, []testrail.Case{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}})
+
+	err := m.Upload()
+	require.Error(t, err)
+	uploadErr, ok := err.(*UploadError)
+	require.True(t, ok)
+	require.Len(t, uploadErr.Batches, 1)
+	assert.Equal(t, 2, uploadErr.Batches[0].Batch)
+	assert.Len(t, m.Pending().Results, 2)
+	assert.ElementsMatch(t, [][]int{{1, 2}, {3, 4}, {5}}, received)
+
+	received = nil
+	require.NoError(t, m.Upload())
+	assert.Equal(t, [][]int{{3, 4}}, received)
+	assert.Empty(t, m.Pending().Results)
+	assert.Len(t, m.Payload().Results, 5)
+}
+
+func TestUploader_EnsureSection(t *testing.T) {
+	var added []testrail.SendableSection
+	c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"id": 10, "name": "pkg", "parent_id": 0}, {"id": 11, "name": "pkg", "parent_id": 10}]`))
+			return
+		}
+		var section testrail.SendableSection
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&section))
+		added = append(added, section)
+		_ = json.NewEncoder(w).Encode(testrail.Section{ID: 100 + len(added), Name: section.Name})
+	})
+	defer server.Close()
+
+	m := NewUploader(server.URL, "user", "password")
+	m.c = c
+
+	id, err := m.EnsureSection(1, 2, 0, []string{"pkg", "pkg"})
+	require.NoError(t, err)
+	assert.Equal(t, 11, id)
+	assert.Empty(t, added)
+
+	id, err = m.EnsureSection(1, 2, 0, []string{"pkg", "sub", "deep"})
+	require.NoError(t, err)
+	assert.Equal(t, 102, id)
+	assert.Equal(t, []testrail.SendableSection{
+		{SuiteID: 2, ParentID: 10, Name: "sub"},
+		{SuiteID: 2, ParentID: 101, Name: "deep"},
+	}, added)
+
+	// sections added before are reused
+	id, err = m.EnsureSection(1, 2, 0, []string{"pkg", "sub"})
+	require.NoError(t, err)
+	assert.Equal(t, 101, id)
+	assert.Len(t, added, 2)
+}
+
+func TestUploader_SectionNames(t *testing.T) {
+	c, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id": 1, "name": "parser"}, {"id": 2, "name": "json", "parent_id": 1},
+			{"id": 3, "name": "stream", "parent_id": 2}, {"id": 4, "name": "client"}]`))
+	})
+	defer server.Close()
+
+	m := NewUploader(server.URL, "user", "password")
+	m.c = c
+
+	names, err := m.SectionNames(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "parser", 2: "parser / json", 3: "parser / json / stream", 4: "client"}, names)
+}
+
+func TestElapsedDuration(t *testing.T) {
+	tests := []struct {
+		seconds  float64
+		duration time.Duration
+	}{
+		{0, time.Second},
+		{0.2, time.Second},
+		{0.5, time.Second},
+		{1.4, time.Second},
+		{1.5, 2 * time.Second},
+		{61.7, 62 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.duration, elapsedDuration(tt.seconds), "%v seconds", tt.seconds)
+	}
+}
+
+func TestUploader_AddTestsSharedCase(t *testing.T) {
+	m := NewUploader("http://localhost", "user", "password")
+	m.InitWithCases(54, []testrail.Case{{ID: 1}, {ID: 2}})
+	m.AddTests([]*types.TestMatcher{
+		{ID: 1, Status: types.TestStatusPassed, Elapsed: 0.4},
+		{ID: 2, Status: types.TestStatusPassed, Elapsed: 0.3},
+		{ID: 1, Status: types.TestStatusPassed, Elapsed: 0.4},
+		{ID: 1, Status: types.TestStatusFailed, Elapsed: 1.9},
+	}, true)
+
+	results := m.Payload().Results
+	require.Len(t, results, 2)
+	assert.Equal(t, 3*time.Second, results[0].Elapsed.Duration)
+	assert.Equal(t, testrail.StatusFailed, results[0].StatusID)
+	assert.Equal(t, time.Second, results[1].Elapsed.Duration)
+	assert.Equal(t, testrail.StatusPassed, results[1].StatusID)
+
+	// order of subtests doesn't matter
+	m.AddTests([]*types.TestMatcher{
+		{ID: 1, Status: types.TestStatusFailed},
+		{ID: 1, Status: types.TestStatusPassed},
+		{ID: 2, Status: types.TestStatusSkipped},
+		{ID: 2, Status: types.TestStatusPassed},
+	}, true)
+	results = m.Payload().Results
+	assert.Equal(t, testrail.StatusFailed, results[0].StatusID)
+	assert.Equal(t, m.statuses[types.TestStatusSkipped], results[1].StatusID)
+	assert.Equal(t, map[int]string{1: types.TestStatusFailed, 2: types.TestStatusSkipped}, m.testStatuses)
+}
+
+func TestUploader_AddTestsSharedCaseComment(t *testing.T) {
+	m := NewUploader("http://localhost", "user", "password")
+	m.SetCommentSize(80)
+	m.InitWithCases(54, []testrail.Case{{ID: 1}})
+	m.AddTests([]*types.TestMatcher{
+		{ID: 1, Status: types.TestStatusFailed, Output: "    example_test.go:22: expected 1\n--- FAIL: TestExample/first\n"},
+		{ID: 1, Status: types.TestStatusFailed, Output: "...\nsome noise\nmore noise\n--- FAIL: TestExample/second\n"},
+	}, true)
+
+	results := m.Payload().Results
+	require.Len(t, results, 1)
+	assert.Equal(t, "    ...\n        example_test.go:22: expected 1\n    more noise\n    --- FAIL: TestExample/second", results[0].Comment)
+
+	m.SetCommentSize(0)
+	m.AddTests([]*types.TestMatcher{{ID: 1, Status: types.TestStatusFailed, Output: "output\n"}}, true)
+	assert.Empty(t, m.Payload().Results[0].Comment)
+}
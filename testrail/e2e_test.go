@@ -0,0 +1,354 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/testrail/fake"
+	"github.com/insolar/testrail-cli/types"
+)
+
+func newFakeUploader(state fake.State) (*Uploader, *fake.Server, *httptest.Server) {
+	fakeServer := fake.New(state)
+	fakeServer.User, fakeServer.Password = "user", "password"
+	fakeServer.Timeout = time.Second
+	server := httptest.NewServer(fakeServer)
+
+	m := NewUploader(server.URL, "user", "password")
+	m.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Deadline: 5 * time.Second})
+	return m, fakeServer, server
+}
+
+func suiteState() fake.State {
+	return fake.State{
+		Cases: []testrail.Case{
+			{ID: 1, Title: "passed", SuiteID: 2, SectionID: 1},
+			{ID: 2, Title: "failed", SuiteID: 2, SectionID: 1},
+			{ID: 3, Title: "not run", SuiteID: 2, SectionID: 2},
+			{ID: 4, Title: "other job", SuiteID: 2, SectionID: 3},
+		},
+		Sections: []testrail.Section{
+			{ID: 1, Name: "client", SuiteID: 2},
+			{ID: 2, Name: "upload", SuiteID: 2, ParentID: 1},
+			{ID: 3, Name: "parser", SuiteID: 2},
+		},
+		Runs: []fake.Run{{Run: testrail.Run{ID: 5, ProjectID: 1, SuiteID: 2, IncludeAll: true}}},
+	}
+}
+
+func suiteTests() []*types.TestMatcher {
+	return []*types.TestMatcher{
+		{ID: 1, Status: types.TestStatusPassed, GoTestName: "TestPassed", Elapsed: 0.5},
+		{ID: 2, Status: types.TestStatusFailed, GoTestName: "TestFailed", Output: "boom"},
+		{ID: 9, Status: types.TestStatusPassed, GoTestName: "TestRemoved"},
+	}
+}
+
+func latestStatuses(state fake.State, runID int) map[int]int {
+	statuses := make(map[int]int)
+	for _, r := range state.Results {
+		if r.RunID == runID {
+			statuses[r.CaseID] = r.StatusID
+		}
+	}
+	return statuses
+}
+
+func TestUploader_EndToEnd(t *testing.T) {
+	na := statusMap[types.TestStatusNotAvailable]
+	tests := []struct {
+		name     string
+		mode     Mode
+		sections []int
+		cases    []int
+		statuses map[int]int
+	}{
+		{
+			name:     "full",
+			mode:     ModeFull,
+			statuses: map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed, 3: na, 4: na},
+		},
+		{
+			name:     "partial",
+			mode:     ModePartial,
+			statuses: map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed},
+		},
+		{
+			name:     "scoped by section with subsections",
+			mode:     ModeScoped,
+			sections: []int{1},
+			statuses: map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed, 3: na},
+		},
+		{
+			name:     "scoped by cases",
+			mode:     ModeScoped,
+			cases:    []int{4},
+			statuses: map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed, 4: na},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, fakeServer, server := newFakeUploader(suiteState())
+			defer server.Close()
+			// cases are fetched in pages like from testrail 6.7+
+			fakeServer.PageSize = 3
+
+			m.SetMode(tt.mode)
+			require.NoError(t, m.Init(5))
+			assert.Equal(t, 2, fakeServer.Calls("get_cases"))
+			require.NoError(t, m.SetScope(tt.sections, tt.cases))
+			m.AddTests(suiteTests(), true)
+			require.NoError(t, m.Upload())
+			assert.Equal(t, tt.statuses, latestStatuses(fakeServer.State(), 5))
+
+			// run with untested cases is left open
+			closed, err := m.Close(false)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.statuses) == 4, closed)
+			assert.Equal(t, closed, fakeServer.State().Runs[0].IsCompleted)
+		})
+	}
+}
+
+func TestUploader_EndToEndFaults(t *testing.T) {
+	t.Run("retried", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+
+		fakeServer.Inject(
+			fake.Fault{Endpoint: "get_run", Status: http.StatusServiceUnavailable, Times: 1},
+			fake.Fault{Endpoint: "add_results_for_cases", Status: http.StatusTooManyRequests, Times: 2},
+		)
+		require.NoError(t, m.Init(5))
+		m.AddTests(suiteTests(), true)
+		require.NoError(t, m.Upload())
+
+		assert.Equal(t, 2, fakeServer.Calls("get_run"))
+		assert.Equal(t, 3, fakeServer.Calls("add_results_for_cases"))
+		assert.Len(t, fakeServer.State().Results, 2)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+		m.c.httpClient.Timeout = 50 * time.Millisecond
+
+		fakeServer.Inject(fake.Fault{Endpoint: "get_cases", Timeout: true, Times: 1})
+		require.NoError(t, m.Init(5))
+		assert.Equal(t, 2, fakeServer.Calls("get_cases"))
+	})
+
+	t.Run("temporary failure is kept pending", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+
+		require.NoError(t, m.Init(5))
+		m.AddTests(suiteTests(), true)
+		fakeServer.Inject(fake.Fault{Endpoint: "add_results_for_cases", Status: http.StatusServiceUnavailable})
+
+		err := m.Upload()
+		require.Error(t, err)
+		assert.True(t, IsTemporary(err))
+		assert.False(t, IsAmbiguous(err))
+		assert.Len(t, m.Pending().Results, 2)
+		assert.Empty(t, fakeServer.State().Results)
+		assert.Equal(t, 3, fakeServer.Calls("add_results_for_cases"))
+	})
+
+	t.Run("applied write is not retried", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+		m.c.httpClient.Timeout = 50 * time.Millisecond
+
+		require.NoError(t, m.Init(5))
+		m.AddTests(suiteTests(), true)
+		fakeServer.Inject(fake.Fault{Endpoint: "add_results_for_cases", Timeout: true, Applied: true, Times: 1})
+
+		err := m.Upload()
+		require.Error(t, err)
+		assert.False(t, IsTemporary(err))
+		assert.True(t, IsAmbiguous(err))
+		assert.Equal(t, 1, fakeServer.Calls("add_results_for_cases"))
+		assert.Len(t, fakeServer.State().Results, 2)
+	})
+
+	t.Run("server error of write is ambiguous", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+
+		fakeServer.Inject(fake.Fault{Endpoint: "add_run", Status: http.StatusBadGateway, Applied: true, Times: 1})
+		_, err := m.CreateRun(1, testrail.SendableRun{SuiteID: 2, Name: "nightly", CaseIDs: []int{1}})
+		require.Error(t, err)
+		assert.True(t, IsAmbiguous(err))
+		assert.Equal(t, 1, fakeServer.Calls("add_run"))
+		assert.Len(t, fakeServer.State().Runs, 2)
+	})
+
+	t.Run("refused connection is retried", func(t *testing.T) {
+		m, _, server := newFakeUploader(suiteState())
+		server.Close()
+
+		_, err := m.c.AddRun(1, testrail.SendableRun{SuiteID: 2})
+		require.Error(t, err)
+		assert.True(t, IsTemporary(err))
+		assert.False(t, IsAmbiguous(err))
+		assert.Contains(t, err.Error(), "giving up after 3 attempts")
+	})
+
+	t.Run("malformed response", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+
+		fakeServer.Inject(fake.Fault{Endpoint: "get_run", Malformed: true})
+		err := m.Init(5)
+		require.Error(t, err)
+		assert.False(t, IsTemporary(err))
+		assert.Equal(t, 1, fakeServer.Calls("get_run"))
+	})
+}
+
+func TestUploader_EndToEndCreateRun(t *testing.T) {
+	m, fakeServer, server := newFakeUploader(suiteState())
+	defer server.Close()
+
+	run, err := m.CreateRun(1, testrail.SendableRun{SuiteID: 2, Name: "nightly", CaseIDs: []int{1, 2, 9}})
+	require.NoError(t, err)
+	assert.Equal(t, 6, run.ID)
+
+	m.AddTests(suiteTests(), true)
+	require.NoError(t, m.Upload())
+	assert.Equal(t, map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed}, latestStatuses(fakeServer.State(), 6))
+
+	_, err = m.CreateRun(1, testrail.SendableRun{SuiteID: 2, Name: "nightly"})
+	assert.EqualError(t, err, "no case ids to create run with")
+	_, err = m.CreateRun(1, testrail.SendableRun{SuiteID: 2, Name: "nightly", CaseIDs: []int{8, 9}})
+	assert.EqualError(t, err, "none of 2 cases exists in suite 2")
+	assert.Equal(t, 1, fakeServer.Calls("add_run"))
+}
+
+func TestUploader_EndToEndPlan(t *testing.T) {
+	state := suiteState()
+	state.Plans = []testrail.Plan{{ID: 60, ProjectID: 1, Entries: []testrail.Entry{{
+		ID: "mysql", SuiteID: 2, Runs: []testrail.Run{{ID: 7, SuiteID: 2, IncludeAll: true, Config: "MySQL, Linux"}},
+	}}}}
+	state.Configs = []testrail.Configuration{
+		{ID: 1, ProjectID: 1, Name: "Databases", Configs: []testrail.Config{{ID: 11, Name: "Postgres"}, {ID: 12, Name: "MySQL"}}},
+		{ID: 2, ProjectID: 1, Name: "OS", Configs: []testrail.Config{{ID: 21, Name: "Linux"}}},
+	}
+	m, fakeServer, server := newFakeUploader(state)
+	defer server.Close()
+
+	run, err := m.InitPlan(60, "linux, mysql", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 7, run.ID)
+	assert.Equal(t, 0, fakeServer.Calls("add_plan_entry"))
+
+	run, err = m.InitPlan(60, "Postgres, Linux", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 8, run.ID)
+	assert.Equal(t, 1, fakeServer.Calls("add_plan_entry"))
+
+	m.AddTests(suiteTests(), true)
+	require.NoError(t, m.Upload())
+	assert.Len(t, latestStatuses(fakeServer.State(), 8), 2)
+}
+
+func TestUploader_EndToEndReplay(t *testing.T) {
+	t.Run("ambiguous results", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+		m.c.httpClient.Timeout = 50 * time.Millisecond
+
+		require.NoError(t, m.Init(5))
+		m.AddTests(suiteTests(), true)
+		fakeServer.Inject(fake.Fault{Endpoint: "add_results_for_cases", Timeout: true, Applied: true, Times: 1})
+		err := m.Upload()
+		require.Error(t, err)
+		assert.True(t, CanReplay(err))
+
+		// ambiguous results are not sent again by upload
+		require.NoError(t, m.Upload())
+		assert.Equal(t, 1, fakeServer.Calls("add_results_for_cases"))
+		assert.Len(t, m.Pending().Results, 2)
+		require.NotNil(t, m.Ambiguous())
+		assert.Equal(t, []int{1, 2}, m.Ambiguous().CaseIDs)
+
+		entry, err := NewSpoolEntry(server.URL, SpoolRun{RunID: 5}, m.Pending(), true)
+		require.NoError(t, err)
+		entry.Ambiguous = m.Ambiguous()
+
+		replay := NewUploader(server.URL, "user", "password")
+		require.NoError(t, replay.InitSpooled(entry))
+		assert.Empty(t, replay.Pending().Results)
+		require.NoError(t, replay.Upload())
+		assert.Len(t, fakeServer.State().Results, 2)
+	})
+
+	t.Run("ambiguous results not added", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+		m.c.httpClient.Timeout = 50 * time.Millisecond
+
+		require.NoError(t, m.Init(5))
+		m.AddTests(suiteTests(), true)
+		fakeServer.Inject(fake.Fault{Endpoint: "add_results_for_cases", Timeout: true, Times: 1})
+		require.Error(t, m.Upload())
+
+		entry, err := NewSpoolEntry(server.URL, SpoolRun{RunID: 5}, m.Pending(), true)
+		require.NoError(t, err)
+		entry.Ambiguous = m.Ambiguous()
+
+		replay := NewUploader(server.URL, "user", "password")
+		require.NoError(t, replay.InitSpooled(entry))
+		require.NoError(t, replay.Upload())
+		assert.Equal(t, map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed}, latestStatuses(fakeServer.State(), 5))
+		assert.Len(t, fakeServer.State().Results, 2)
+	})
+
+	t.Run("new run", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+
+		m.AddTests(suiteTests(), false)
+		entry, err := NewSpoolEntry(server.URL, SpoolRun{ProjectID: 1, SuiteID: 2, Name: "nightly"}, m.Pending(), false)
+		require.NoError(t, err)
+
+		replay := NewUploader(server.URL, "user", "password")
+		require.NoError(t, replay.ResolveRun(&entry))
+		assert.Equal(t, 6, entry.RunID)
+		require.NoError(t, replay.InitSpooled(entry))
+		require.NoError(t, replay.Upload())
+		assert.Equal(t, map[int]int{1: testrail.StatusPassed, 2: testrail.StatusFailed}, latestStatuses(fakeServer.State(), 6))
+		assert.Equal(t, []int{1, 2}, fakeServer.State().Runs[1].CaseIDs)
+	})
+
+	t.Run("run could be created", func(t *testing.T) {
+		m, fakeServer, server := newFakeUploader(suiteState())
+		defer server.Close()
+
+		m.AddTests(suiteTests(), false)
+		entry, err := NewSpoolEntry(server.URL, SpoolRun{ProjectID: 1, SuiteID: 2, Name: "nightly"}, m.Pending(), false)
+		require.NoError(t, err)
+
+		fakeServer.Inject(fake.Fault{Endpoint: "add_run", Status: http.StatusBadGateway, Applied: true, Times: 1})
+		replay := NewUploader(server.URL, "user", "password")
+		require.Error(t, replay.ResolveRun(&entry))
+		assert.True(t, entry.RunAmbiguous)
+
+		err = replay.ResolveRun(&entry)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "set run_id of entry")
+		assert.Equal(t, 1, fakeServer.Calls("add_run"))
+	})
+}
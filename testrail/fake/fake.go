@@ -0,0 +1,743 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+// Package fake is in-memory stand-in of testrail api v2 subset used by cli:
+// runs, plans, cases, sections and results
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/educlos/testrail"
+)
+
+const apiPrefix = "/api/v2/"
+
+// DefaultTimeout is how long server hangs on timeout fault if client doesn't give up earlier
+const DefaultTimeout = 2 * time.Minute
+
+// requestError is testrail error response
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// request is parsed api call, ex.: get_cases/3&suite_id=12
+type request struct {
+	endpoint string
+	id       int
+	params   url.Values
+	body     []byte
+	baseURL  string
+	pageSize int
+}
+
+type handler func(s *State, req request) (interface{}, error)
+
+var handlers = map[string]handler{
+	"get_run":               getRun,
+	"add_run":               addRun,
+	"close_run":             closeRun,
+	"get_cases":             getCases,
+	"add_case":              addCase,
+	"update_case":           updateCase,
+	"get_sections":          getSections,
+	"add_section":           addSection,
+	"add_results_for_cases": addResultsForCases,
+	"get_results_for_run":   getResultsForRun,
+	"get_tests":             getTests,
+	"get_plan":              getPlan,
+	"add_plan_entry":        addPlanEntry,
+	"get_configs":           getConfigs,
+	"get_statuses":          getStatuses,
+	"get_user_by_email":     getUserByEmail,
+}
+
+// Server serves testrail api from state, changes are saved to state file if it is set
+type Server struct {
+	// User and Password are checked if set
+	User     string
+	Password string
+	// Timeout limits hanging on timeout fault
+	Timeout time.Duration
+	// PageSize makes list endpoints respond with pages like testrail 6.7+, zero keeps plain arrays
+	PageSize int
+
+	mu     sync.Mutex
+	state  State
+	path   string
+	faults []*Fault
+	calls  map[string]int
+}
+
+// New creates server with state, defaults are filled for statuses and users
+func New(state State) *Server {
+	state.normalize()
+	return &Server{
+		Timeout: DefaultTimeout,
+		state:   state,
+		calls:   make(map[string]int),
+	}
+}
+
+// Load creates server with state from json file, changes are saved to the same file
+func Load(path string) (*Server, error) {
+	state, err := LoadState(path)
+	if err != nil {
+		return nil, err
+	}
+	s := New(state)
+	s.path = path
+	return s, nil
+}
+
+// Inject adds faults, first matching fault breaks response
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range faults {
+		f := faults[i]
+		s.faults = append(s.faults, &f)
+	}
+}
+
+// State returns copy of current state
+func (s *Server) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		panic(err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		panic(err)
+	}
+	return state
+}
+
+// Calls returns number of requests to endpoint, faulty ones included
+func (s *Server) Calls(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[endpoint]
+}
+
+// fault returns fault for endpoint and counts its use
+func (s *Server) fault(endpoint string) *Fault {
+	for i, f := range s.faults {
+		if !f.matches(endpoint) {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// parseRequest splits query of /index.php?/api/v2/get_cases/3&suite_id=12
+func parseRequest(r *http.Request) (request, error) {
+	query := r.URL.RawQuery
+	if !strings.HasPrefix(query, apiPrefix) {
+		return request{}, &requestError{status: http.StatusNotFound, message: "unknown url " + r.URL.String()}
+	}
+	query = strings.TrimPrefix(query, apiPrefix)
+
+	req := request{baseURL: "http://" + r.Host + "/"}
+	if i := strings.Index(query, "&"); i >= 0 {
+		params, err := url.ParseQuery(query[i+1:])
+		if err != nil {
+			return request{}, badRequest("invalid parameters: %v", err)
+		}
+		req.params, query = params, query[:i]
+	}
+	req.endpoint = query
+	if i := strings.Index(query, "/"); i >= 0 {
+		id, err := strconv.Atoi(query[i+1:])
+		if err != nil {
+			return request{}, badRequest("invalid id %s", query[i+1:])
+		}
+		req.endpoint, req.id = query[:i], id
+	}
+	return req, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		e := err.(*requestError)
+		writeError(w, e.status, e.message)
+		return
+	}
+	if s.User != "" || s.Password != "" {
+		if user, pass, ok := r.BasicAuth(); !ok || user != s.User || pass != s.Password {
+			writeError(w, http.StatusUnauthorized, "Authentication failed: invalid or missing user/password or session cookie.")
+			return
+		}
+	}
+
+	req.pageSize = s.PageSize
+
+	s.mu.Lock()
+	s.calls[req.endpoint]++
+	fault := s.fault(req.endpoint)
+	s.mu.Unlock()
+	if fault != nil && !fault.Applied {
+		fault.apply(w, r, s.Timeout)
+		return
+	}
+
+	h, ok := handlers[req.endpoint]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Unknown method '"+req.endpoint+"'")
+		return
+	}
+	readOnly := strings.HasPrefix(req.endpoint, "get_")
+	if (readOnly && r.Method != http.MethodGet) || (!readOnly && r.Method != http.MethodPost) {
+		writeError(w, http.StatusBadRequest, "Wrong http method "+r.Method+" for "+req.endpoint)
+		return
+	}
+	if req.body, err = ioutil.ReadAll(r.Body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	resp, err := h(&s.state, req)
+	if err == nil && !readOnly && s.path != "" {
+		if saveErr := s.state.Save(s.path); saveErr != nil {
+			log.Print(saveErr)
+		}
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		fault.apply(w, r, s.Timeout)
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if e, ok := err.(*requestError); ok {
+			status = e.status
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// paginate returns items from offset up to limit of page size in paginated response with link
+// to the next page, items are returned as is if server has no page size
+func paginate(req request, key string, count int, items func(from, to int) interface{}) interface{} {
+	if req.pageSize <= 0 {
+		return items(0, count)
+	}
+
+	offset, _ := strconv.Atoi(req.params.Get("offset"))
+	if offset < 0 || offset > count {
+		offset = count
+	}
+	limit, _ := strconv.Atoi(req.params.Get("limit"))
+	if limit <= 0 || limit > req.pageSize {
+		limit = req.pageSize
+	}
+	end := offset + limit
+	if end > count {
+		end = count
+	}
+
+	var next *string
+	if end < count {
+		params := url.Values{}
+		for name, values := range req.params {
+			params[name] = values
+		}
+		params.Set("offset", strconv.Itoa(end))
+		params.Set("limit", strconv.Itoa(limit))
+		link := fmt.Sprintf("%s%s/%d&%s", apiPrefix, req.endpoint, req.id, params.Encode())
+		next = &link
+	}
+	return map[string]interface{}{
+		"offset": offset,
+		"limit":  limit,
+		"size":   end - offset,
+		"_links": map[string]*string{"next": next, "prev": nil},
+		key:      items(offset, end),
+	}
+}
+
+func decode(req request, v interface{}) error {
+	if err := json.Unmarshal(req.body, v); err != nil {
+		return badRequest("invalid %s request: %v", req.endpoint, err)
+	}
+	return nil
+}
+
+func getRun(s *State, req request) (interface{}, error) {
+	run := s.run(req.id)
+	if run == nil {
+		return nil, badRequest("Field :run_id is not a valid test run.")
+	}
+	return s.withCounts(run), nil
+}
+
+func newRun(s *State, projectID int, sendable testrail.SendableRun, baseURL string) (*Run, error) {
+	includeAll := sendable.IncludeAll == nil || *sendable.IncludeAll
+	for _, id := range sendable.CaseIDs {
+		if c := s.testCase(id); c == nil || c.SuiteID != sendable.SuiteID {
+			return nil, badRequest("Field :case_ids contains invalid case %d.", id)
+		}
+	}
+
+	var ids []int
+	for _, r := range s.Runs {
+		ids = append(ids, r.ID)
+	}
+	run := Run{Run: testrail.Run{
+		ID:           nextID(ids...),
+		ProjectID:    projectID,
+		SuiteID:      sendable.SuiteID,
+		Name:         sendable.Name,
+		Description:  sendable.Description,
+		MilestoneID:  sendable.MilestoneID,
+		AssignedToID: sendable.AssignedToID,
+		IncludeAll:   includeAll,
+		CreatedOn:    int(time.Now().Unix()),
+	}}
+	run.URL = baseURL + "index.php?/runs/view/" + strconv.Itoa(run.ID)
+	if !includeAll {
+		run.CaseIDs = sendable.CaseIDs
+	}
+	s.Runs = append(s.Runs, run)
+	return &s.Runs[len(s.Runs)-1], nil
+}
+
+func addRun(s *State, req request) (interface{}, error) {
+	var sendable testrail.SendableRun
+	if err := decode(req, &sendable); err != nil {
+		return nil, err
+	}
+	if sendable.SuiteID == 0 {
+		return nil, badRequest("Field :suite_id is a required field.")
+	}
+	run, err := newRun(s, req.id, sendable, req.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.withCounts(run), nil
+}
+
+func closeRun(s *State, req request) (interface{}, error) {
+	run := s.run(req.id)
+	if run == nil {
+		return nil, badRequest("Field :run_id is not a valid test run.")
+	}
+	if run.PlanID != 0 {
+		return nil, badRequest("Field :run_id is part of a test plan and cannot be closed individually.")
+	}
+	run.IsCompleted = true
+	run.CompletedOn = int(time.Now().Unix())
+	return s.withCounts(run), nil
+}
+
+func getCases(s *State, req request) (interface{}, error) {
+	suiteID, _ := strconv.Atoi(req.params.Get("suite_id"))
+	cases := []testrail.Case{}
+	for _, c := range s.Cases {
+		if suiteID == 0 || c.SuiteID == suiteID {
+			cases = append(cases, c)
+		}
+	}
+	return paginate(req, "cases", len(cases), func(from, to int) interface{} {
+		return cases[from:to]
+	}), nil
+}
+
+func addCase(s *State, req request) (interface{}, error) {
+	var sendable testrail.SendableCase
+	if err := decode(req, &sendable); err != nil {
+		return nil, err
+	}
+	section := s.section(req.id)
+	if section == nil {
+		return nil, badRequest("Field :section_id is not a valid section.")
+	}
+	if sendable.Title == "" {
+		return nil, badRequest("Field :title is a required field.")
+	}
+
+	var ids []int
+	for _, c := range s.Cases {
+		ids = append(ids, c.ID)
+	}
+	c := testrail.Case{
+		ID:        nextID(ids...),
+		Title:     sendable.Title,
+		SectionID: section.ID,
+		SuiteID:   section.SuiteID,
+		CreatedOn: int(time.Now().Unix()),
+	}
+	s.Cases = append(s.Cases, c)
+	return c, nil
+}
+
+func updateCase(s *State, req request) (interface{}, error) {
+	var sendable testrail.SendableCase
+	if err := decode(req, &sendable); err != nil {
+		return nil, err
+	}
+	c := s.testCase(req.id)
+	if c == nil {
+		return nil, badRequest("Field :case_id is not a valid test case.")
+	}
+	if sendable.Title != "" {
+		c.Title = sendable.Title
+	}
+	return *c, nil
+}
+
+func getSections(s *State, req request) (interface{}, error) {
+	suiteID, _ := strconv.Atoi(req.params.Get("suite_id"))
+	sections := []testrail.Section{}
+	for _, section := range s.Sections {
+		if suiteID == 0 || section.SuiteID == suiteID {
+			sections = append(sections, section)
+		}
+	}
+	return paginate(req, "sections", len(sections), func(from, to int) interface{} {
+		return sections[from:to]
+	}), nil
+}
+
+func addSection(s *State, req request) (interface{}, error) {
+	var sendable testrail.SendableSection
+	if err := decode(req, &sendable); err != nil {
+		return nil, err
+	}
+	if sendable.Name == "" {
+		return nil, badRequest("Field :name is a required field.")
+	}
+	depth := 0
+	if sendable.ParentID != 0 {
+		parent := s.section(sendable.ParentID)
+		if parent == nil || parent.SuiteID != sendable.SuiteID {
+			return nil, badRequest("Field :parent_id is not a valid section.")
+		}
+		depth = parent.Depth + 1
+	}
+
+	var ids []int
+	for _, section := range s.Sections {
+		ids = append(ids, section.ID)
+	}
+	section := testrail.Section{
+		ID:          nextID(ids...),
+		Name:        sendable.Name,
+		Description: sendable.Description,
+		ParentID:    sendable.ParentID,
+		SuiteID:     sendable.SuiteID,
+		Depth:       depth,
+	}
+	s.Sections = append(s.Sections, section)
+	return section, nil
+}
+
+func addResultsForCases(s *State, req request) (interface{}, error) {
+	var sendable testrail.SendableResultsForCase
+	if err := decode(req, &sendable); err != nil {
+		return nil, err
+	}
+	run := s.run(req.id)
+	if run == nil {
+		return nil, badRequest("Field :run_id is not a valid test run.")
+	}
+	if run.IsCompleted {
+		return nil, badRequest("Field :run_id refers to a closed test run.")
+	}
+
+	inRun := make(map[int]bool)
+	for _, id := range s.runCases(run) {
+		inRun[id] = true
+	}
+	// testrail rejects whole request if any result is invalid
+	for i, r := range sendable.Results {
+		if !inRun[r.CaseID] {
+			return nil, badRequest("Field :results cannot be parsed (case C%d unknown or not part of the test run, result %d).", r.CaseID, i)
+		}
+		if status := s.status(r.StatusID); r.StatusID != 0 && (status == nil || status.IsUntested) {
+			return nil, badRequest("Field :results.status_id uses an invalid status (%d).", r.StatusID)
+		}
+		if r.AssignedToID != 0 && s.user(r.AssignedToID) == nil {
+			return nil, badRequest("Field :results.assignedto_id is not a valid user (%d).", r.AssignedToID)
+		}
+	}
+
+	created := []resultResponse{}
+	now := time.Now().Unix()
+	for _, r := range sendable.Results {
+		s.Results = append(s.Results, Result{RunID: run.ID, CreatedOn: now, ResultsForCase: r})
+		created = append(created, resultResponse{
+			ID:           len(s.Results),
+			TestID:       testID(run.ID, r.CaseID),
+			StatusID:     r.StatusID,
+			CreatedOn:    now,
+			Comment:      r.Comment,
+			Version:      r.Version,
+			Defects:      r.Defects,
+			AssignedToID: r.AssignedToID,
+		})
+	}
+	return created, nil
+}
+
+// getResultsForRun returns results of run newest first, created_after filters them by time
+func getResultsForRun(s *State, req request) (interface{}, error) {
+	run := s.run(req.id)
+	if run == nil {
+		return nil, badRequest("Field :run_id is not a valid test run.")
+	}
+	createdAfter, _ := strconv.ParseInt(req.params.Get("created_after"), 10, 64)
+
+	results := []resultResponse{}
+	for i := len(s.Results) - 1; i >= 0; i-- {
+		r := s.Results[i]
+		if r.RunID != run.ID || r.CreatedOn < createdAfter {
+			continue
+		}
+		results = append(results, resultResponse{
+			ID:           i + 1,
+			TestID:       testID(run.ID, r.CaseID),
+			StatusID:     r.StatusID,
+			CreatedOn:    r.CreatedOn,
+			Comment:      r.Comment,
+			Version:      r.Version,
+			Defects:      r.Defects,
+			AssignedToID: r.AssignedToID,
+		})
+	}
+	return paginate(req, "results", len(results), func(from, to int) interface{} {
+		return results[from:to]
+	}), nil
+}
+
+// testResponse is run test, that is case added to run
+type testResponse struct {
+	ID       int    `json:"id"`
+	CaseID   int    `json:"case_id"`
+	RunID    int    `json:"run_id"`
+	StatusID int    `json:"status_id"`
+	Title    string `json:"title"`
+}
+
+func getTests(s *State, req request) (interface{}, error) {
+	run := s.run(req.id)
+	if run == nil {
+		return nil, badRequest("Field :run_id is not a valid test run.")
+	}
+	latest := make(map[int]int)
+	for _, r := range s.Results {
+		if r.RunID == run.ID {
+			latest[r.CaseID] = r.StatusID
+		}
+	}
+
+	tests := []testResponse{}
+	for _, id := range s.runCases(run) {
+		test := testResponse{ID: testID(run.ID, id), CaseID: id, RunID: run.ID, StatusID: testrail.StatusUntested}
+		if status, ok := latest[id]; ok {
+			test.StatusID = status
+		}
+		if c := s.testCase(id); c != nil {
+			test.Title = c.Title
+		}
+		tests = append(tests, test)
+	}
+	return paginate(req, "tests", len(tests), func(from, to int) interface{} {
+		return tests[from:to]
+	}), nil
+}
+
+// resultResponse is created result as testrail sends it, testrail.Result
+// doesn't marshal its timestamp the way it is unmarshalled
+type resultResponse struct {
+	ID           int    `json:"id"`
+	TestID       int    `json:"test_id"`
+	StatusID     int    `json:"status_id"`
+	CreatedOn    int64  `json:"created_on"`
+	Comment      string `json:"comment"`
+	Version      string `json:"version"`
+	Defects      string `json:"defects"`
+	AssignedToID int    `json:"assignedto_id"`
+}
+
+// planWithRuns returns plan with entries filled with their runs
+func (s *State) planWithRuns(plan *testrail.Plan) testrail.Plan {
+	withRuns := *plan
+	withRuns.Entries = make([]testrail.Entry, len(plan.Entries))
+	for i, entry := range plan.Entries {
+		entry.Runs = nil
+		for j := range s.Runs {
+			if run := &s.Runs[j]; run.PlanID == plan.ID && run.EntryID == entry.ID {
+				entry.Runs = append(entry.Runs, s.withCounts(run))
+			}
+		}
+		withRuns.Entries[i] = entry
+	}
+	return withRuns
+}
+
+func getPlan(s *State, req request) (interface{}, error) {
+	plan := s.plan(req.id)
+	if plan == nil {
+		return nil, badRequest("Field :plan_id is not a valid test plan.")
+	}
+	return s.planWithRuns(plan), nil
+}
+
+// configCombinations returns combinations of configs, one of every group, like testrail does
+// when entry runs aren't given
+func (s *State) configCombinations(projectID int, ids []int) ([][]testrail.Config, error) {
+	var (
+		groups []int
+		byGrp  = make(map[int][]testrail.Config)
+	)
+	for _, id := range ids {
+		found := false
+		for _, group := range s.Configs {
+			if group.ProjectID != projectID {
+				continue
+			}
+			for _, c := range group.Configs {
+				if c.ID == id {
+					if _, ok := byGrp[group.ID]; !ok {
+						groups = append(groups, group.ID)
+					}
+					byGrp[group.ID] = append(byGrp[group.ID], c)
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, badRequest("Field :config_ids contains invalid configuration %d.", id)
+		}
+	}
+
+	combinations := [][]testrail.Config{nil}
+	for _, group := range groups {
+		var next [][]testrail.Config
+		for _, combination := range combinations {
+			for _, c := range byGrp[group] {
+				next = append(next, append(append([]testrail.Config(nil), combination...), c))
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+func addPlanEntry(s *State, req request) (interface{}, error) {
+	var sendable testrail.SendableEntry
+	if err := decode(req, &sendable); err != nil {
+		return nil, err
+	}
+	plan := s.plan(req.id)
+	if plan == nil {
+		return nil, badRequest("Field :plan_id is not a valid test plan.")
+	}
+	if sendable.SuiteID == 0 {
+		return nil, badRequest("Field :suite_id is a required field.")
+	}
+	combinations, err := s.configCombinations(plan.ProjectID, sendable.ConfigIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := testrail.Entry{
+		ID:      fmt.Sprintf("entry-%d-%d", plan.ID, len(plan.Entries)+1),
+		Name:    sendable.Name,
+		SuiteID: sendable.SuiteID,
+	}
+	includeAll := sendable.IncludeAll
+	for i, combination := range combinations {
+		run, err := newRun(s, plan.ProjectID, testrail.SendableRun{
+			SuiteID:      sendable.SuiteID,
+			Name:         sendable.Name,
+			AssignedToID: sendable.AssignedtoID,
+			IncludeAll:   &includeAll,
+			CaseIDs:      sendable.CaseIDs,
+		}, req.baseURL)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, c := range combination {
+			names = append(names, c.Name)
+			run.ConfigIDs = append(run.ConfigIDs, c.ID)
+		}
+		run.Config = strings.Join(names, ", ")
+		run.PlanID, run.EntryID, run.EntryIndex = plan.ID, entry.ID, i+1
+		entry.Runs = append(entry.Runs, s.withCounts(run))
+	}
+
+	runs := entry.Runs
+	entry.Runs = nil
+	plan.Entries = append(plan.Entries, entry)
+	entry.Runs = runs
+	return entry, nil
+}
+
+func getConfigs(s *State, req request) (interface{}, error) {
+	configs := []testrail.Configuration{}
+	for _, group := range s.Configs {
+		if group.ProjectID == req.id {
+			configs = append(configs, group)
+		}
+	}
+	return configs, nil
+}
+
+func getStatuses(s *State, req request) (interface{}, error) {
+	return s.Statuses, nil
+}
+
+func getUserByEmail(s *State, req request) (interface{}, error) {
+	email := req.params.Get("email")
+	for _, u := range s.Users {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, badRequest("Field :email is not a valid email address.")
+}
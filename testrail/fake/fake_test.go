@@ -0,0 +1,235 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFault(t *testing.T) {
+	tests := []struct {
+		spec  string
+		fault Fault
+	}{
+		{"500", Fault{Status: 500}},
+		{"add_results_for_cases=429*2", Fault{Endpoint: "add_results_for_cases", Status: 429, Times: 2}},
+		{"get_run=timeout", Fault{Endpoint: "get_run", Timeout: true}},
+		{"get_cases=malformed*1", Fault{Endpoint: "get_cases", Malformed: true, Times: 1}},
+		{"add_run=applied-timeout*1", Fault{Endpoint: "add_run", Timeout: true, Applied: true, Times: 1}},
+		{"applied-502", Fault{Status: 502, Applied: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			fault, err := ParseFault(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.fault, fault)
+		})
+	}
+
+	for _, spec := range []string{"200", "get_run=slow", "get_run=500*0", "get_run=500*x", "applied-"} {
+		_, err := ParseFault(spec)
+		assert.Error(t, err, spec)
+	}
+
+	faults, err := ParseFaults(" get_run=502*1  add_run=malformed ")
+	require.NoError(t, err)
+	assert.Len(t, faults, 2)
+}
+
+func call(t *testing.T, url, method, uri string, body interface{}, v interface{}) int {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, url+"/index.php?/api/v2/"+uri, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.SetBasicAuth("user", "password")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	if v != nil && resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.Unmarshal(respBody, v))
+	}
+	return resp.StatusCode
+}
+
+func testState() State {
+	return State{
+		Cases: []testrail.Case{
+			{ID: 1, Title: "first", SuiteID: 2, SectionID: 1},
+			{ID: 2, Title: "second", SuiteID: 2, SectionID: 1},
+			{ID: 3, Title: "other suite", SuiteID: 3},
+		},
+		Sections: []testrail.Section{{ID: 1, Name: "client", SuiteID: 2}},
+		Runs:     []Run{{Run: testrail.Run{ID: 5, ProjectID: 1, SuiteID: 2, IncludeAll: true}}},
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := New(testState())
+	s.User, s.Password = "user", "password"
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var run testrail.Run
+	require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_run/5", nil, &run))
+	assert.Equal(t, 2, run.UntestedCount)
+
+	var cases []testrail.Case
+	require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_cases/1&suite_id=2", nil, &cases))
+	assert.Len(t, cases, 2)
+
+	t.Run("results", func(t *testing.T) {
+		results := testrail.SendableResultsForCase{Results: []testrail.ResultsForCase{
+			{CaseID: 1, SendableResult: testrail.SendableResult{StatusID: testrail.StatusPassed}},
+			{CaseID: 3, SendableResult: testrail.SendableResult{StatusID: testrail.StatusPassed}},
+		}}
+		// whole request is rejected because of case out of run
+		assert.Equal(t, http.StatusBadRequest, call(t, server.URL, "POST", "add_results_for_cases/5", results, nil))
+		assert.Empty(t, s.State().Results)
+
+		results.Results = results.Results[:1]
+		require.Equal(t, http.StatusOK, call(t, server.URL, "POST", "add_results_for_cases/5", results, nil))
+		require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_run/5", nil, &run))
+		assert.Equal(t, 1, run.PassedCount)
+		assert.Equal(t, 1, run.UntestedCount)
+
+		results.Results[0].StatusID = testrail.StatusUntested
+		assert.Equal(t, http.StatusBadRequest, call(t, server.URL, "POST", "add_results_for_cases/5", results, nil))
+	})
+
+	t.Run("add run with cases", func(t *testing.T) {
+		includeAll := false
+		newRun := testrail.SendableRun{SuiteID: 2, IncludeAll: &includeAll, CaseIDs: []int{2}}
+		require.Equal(t, http.StatusOK, call(t, server.URL, "POST", "add_run/1", newRun, &run))
+		assert.Equal(t, 6, run.ID)
+		assert.Equal(t, 1, run.UntestedCount)
+		assert.Equal(t, server.URL+"/index.php?/runs/view/6", run.URL)
+
+		newRun.CaseIDs = []int{3}
+		assert.Equal(t, http.StatusBadRequest, call(t, server.URL, "POST", "add_run/1", newRun, nil))
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		s.PageSize = 1
+		defer func() { s.PageSize = 0 }()
+
+		var page struct {
+			Size  int `json:"size"`
+			Links struct {
+				Next *string `json:"next"`
+			} `json:"_links"`
+			Cases []testrail.Case `json:"cases"`
+		}
+		require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_cases/1&suite_id=2", nil, &page))
+		require.NotNil(t, page.Links.Next)
+		assert.Equal(t, "/api/v2/get_cases/1&limit=1&offset=1&suite_id=2", *page.Links.Next)
+		assert.Len(t, page.Cases, 1)
+
+		require.Equal(t, http.StatusOK, call(t, server.URL, "GET", strings.TrimPrefix(*page.Links.Next, "/api/v2/"), nil, &page))
+		assert.Nil(t, page.Links.Next)
+		assert.Equal(t, 1, page.Size)
+
+		var sections struct {
+			Sections []testrail.Section `json:"sections"`
+		}
+		require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_sections/1&suite_id=2", nil, &sections))
+		assert.Len(t, sections.Sections, 1)
+	})
+
+	t.Run("faults", func(t *testing.T) {
+		s.Inject(Fault{Endpoint: "get_run", Status: http.StatusTooManyRequests, Times: 2}, Fault{Endpoint: "get_cases", Malformed: true})
+		assert.Equal(t, http.StatusTooManyRequests, call(t, server.URL, "GET", "get_run/5", nil, nil))
+		assert.Equal(t, http.StatusTooManyRequests, call(t, server.URL, "GET", "get_run/5", nil, nil))
+		assert.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_run/5", nil, nil))
+
+		var cases []testrail.Case
+		req, err := http.NewRequest("GET", server.URL+"/index.php?/api/v2/get_cases/1&suite_id=2", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("user", "password")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Error(t, json.NewDecoder(resp.Body).Decode(&cases))
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/index.php?/api/v2/get_run/5")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestServer_Plan(t *testing.T) {
+	state := testState()
+	state.Plans = []testrail.Plan{{ID: 60, ProjectID: 1, Entries: []testrail.Entry{{
+		ID: "existing", SuiteID: 2, Runs: []testrail.Run{{ID: 7, SuiteID: 2, IncludeAll: true, Config: "MySQL"}},
+	}}}}
+	state.Configs = []testrail.Configuration{
+		{ID: 1, ProjectID: 1, Name: "Databases", Configs: []testrail.Config{{ID: 11, Name: "Postgres"}, {ID: 12, Name: "MySQL"}}},
+		{ID: 2, ProjectID: 1, Name: "OS", Configs: []testrail.Config{{ID: 21, Name: "Linux"}}},
+	}
+	server := httptest.NewServer(New(state))
+	defer server.Close()
+
+	var plan testrail.Plan
+	require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_plan/60", nil, &plan))
+	require.Len(t, plan.Entries, 1)
+	require.Len(t, plan.Entries[0].Runs, 1)
+	assert.Equal(t, 60, plan.Entries[0].Runs[0].PlanID)
+
+	var entry testrail.Entry
+	newEntry := testrail.SendableEntry{SuiteID: 2, IncludeAll: true, ConfigIDs: []int{11, 21}}
+	require.Equal(t, http.StatusOK, call(t, server.URL, "POST", "add_plan_entry/60", newEntry, &entry))
+	require.Len(t, entry.Runs, 1)
+	assert.Equal(t, "Postgres, Linux", entry.Runs[0].Config)
+	assert.Equal(t, []int{11, 21}, entry.Runs[0].ConfigIDs)
+
+	require.Equal(t, http.StatusOK, call(t, server.URL, "GET", "get_plan/60", nil, &plan))
+	assert.Len(t, plan.Entries, 2)
+	// plan runs are closed with plan
+	assert.Equal(t, http.StatusBadRequest, call(t, server.URL, "POST", "close_run/8", nil, nil))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	s, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStatuses, s.State().Statuses)
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+	var section testrail.Section
+	require.Equal(t, http.StatusOK, call(t, server.URL, "POST", "add_section/1", testrail.SendableSection{SuiteID: 2, Name: "client"}, &section))
+	assert.Equal(t, 1, section.ID)
+
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, []testrail.Section{section}, state.Sections)
+}
@@ -0,0 +1,105 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault breaks responses of endpoint, ex.: add_results_for_cases, request is not handled
+// unless fault is Applied
+type Fault struct {
+	// Endpoint is api method name, empty one matches every request
+	Endpoint string
+	// Status is http status of response
+	Status int
+	// RetryAfter is sent in Retry-After header with Status, in seconds
+	RetryAfter int
+	// Timeout makes server hang until client gives up or Server.Timeout passes
+	Timeout bool
+	// Malformed makes server respond with broken json
+	Malformed bool
+	// Applied makes server handle request before breaking response, so change is made,
+	// but client doesn't know it
+	Applied bool
+	// Times is number of broken responses, zero means every response
+	Times int
+}
+
+// ParseFault parses fault spec [ENDPOINT=][applied-]KIND[*TIMES], kind is http status, timeout or malformed,
+// ex.: add_results_for_cases=429*2, get_run=timeout, add_results_for_cases=applied-timeout*1
+func ParseFault(spec string) (Fault, error) {
+	var f Fault
+	kind := strings.TrimSpace(spec)
+	if i := strings.Index(kind, "="); i >= 0 {
+		f.Endpoint, kind = strings.TrimSpace(kind[:i]), strings.TrimSpace(kind[i+1:])
+	}
+	if strings.HasPrefix(kind, "applied-") {
+		f.Applied, kind = true, strings.TrimPrefix(kind, "applied-")
+	}
+	if i := strings.Index(kind, "*"); i >= 0 {
+		times, err := strconv.Atoi(kind[i+1:])
+		if err != nil || times <= 0 {
+			return Fault{}, fmt.Errorf("invalid number of times in fault %q", spec)
+		}
+		f.Times, kind = times, kind[:i]
+	}
+
+	switch kind {
+	case "timeout":
+		f.Timeout = true
+	case "malformed":
+		f.Malformed = true
+	default:
+		status, err := strconv.Atoi(kind)
+		if err != nil || status < http.StatusBadRequest || status > 599 {
+			return Fault{}, fmt.Errorf("unsupported fault %q, use http status, timeout or malformed", spec)
+		}
+		f.Status = status
+	}
+	return f, nil
+}
+
+// ParseFaults parses space separated fault specs
+func ParseFaults(specs string) ([]Fault, error) {
+	var faults []Fault
+	for _, spec := range strings.Fields(specs) {
+		f, err := ParseFault(spec)
+		if err != nil {
+			return nil, err
+		}
+		faults = append(faults, f)
+	}
+	return faults, nil
+}
+
+func (f *Fault) matches(endpoint string) bool {
+	return f.Endpoint == "" || f.Endpoint == endpoint
+}
+
+// apply writes broken response instead of handling request
+func (f *Fault) apply(w http.ResponseWriter, r *http.Request, timeout time.Duration) {
+	switch {
+	case f.Timeout:
+		select {
+		case <-r.Context().Done():
+		case <-time.After(timeout):
+		}
+		w.WriteHeader(http.StatusGatewayTimeout)
+	case f.Malformed:
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error": "malformed`))
+	default:
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+		}
+		writeError(w, f.Status, fmt.Sprintf("injected fault %d", f.Status))
+	}
+}
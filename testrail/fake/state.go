@@ -0,0 +1,229 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/educlos/testrail"
+)
+
+// Run is testrail run with cases it includes when it isn't created with all suite cases
+type Run struct {
+	testrail.Run
+	CaseIDs []int `json:"case_ids,omitempty"`
+}
+
+// Result is result added to run
+type Result struct {
+	RunID     int   `json:"run_id"`
+	CreatedOn int64 `json:"created_on,omitempty"`
+	testrail.ResultsForCase
+}
+
+// State is data served by fake testrail, runs of plan entries are kept in Runs
+// with plan and entry ids set
+type State struct {
+	Cases    []testrail.Case          `json:"cases"`
+	Sections []testrail.Section       `json:"sections"`
+	Runs     []Run                    `json:"runs"`
+	Plans    []testrail.Plan          `json:"plans"`
+	Configs  []testrail.Configuration `json:"configs"`
+	Statuses []testrail.Status        `json:"statuses"`
+	Users    []testrail.User          `json:"users"`
+	Results  []Result                 `json:"results"`
+}
+
+// DefaultStatuses are statuses of testrail instance cli is written for, custom statuses 6 and 7
+// are used for skipped and n/a results
+var DefaultStatuses = []testrail.Status{
+	{ID: testrail.StatusPassed, Name: "passed", Label: "Passed", IsSystem: true, IsFinal: true},
+	{ID: testrail.StatusBlocked, Name: "blocked", Label: "Blocked", IsSystem: true, IsFinal: true},
+	{ID: testrail.StatusUntested, Name: "untested", Label: "Untested", IsSystem: true, IsUntested: true},
+	{ID: testrail.StatusRetest, Name: "retest", Label: "Retest", IsSystem: true},
+	{ID: testrail.StatusFailed, Name: "failed", Label: "Failed", IsSystem: true, IsFinal: true},
+	{ID: 6, Name: "skipped", Label: "Skipped", IsFinal: true},
+	{ID: 7, Name: "na", Label: "N/A", IsFinal: true},
+}
+
+// DefaultUsers has autotest user results are assigned to
+var DefaultUsers = []testrail.User{
+	{ID: 10, Name: "Autotest", Email: "autotest@example.com", IsActive: true},
+}
+
+// normalize fills defaults and moves runs nested in plan entries to state runs
+func (s *State) normalize() {
+	if len(s.Statuses) == 0 {
+		s.Statuses = append([]testrail.Status(nil), DefaultStatuses...)
+	}
+	if len(s.Users) == 0 {
+		s.Users = append([]testrail.User(nil), DefaultUsers...)
+	}
+
+	for i := range s.Plans {
+		plan := &s.Plans[i]
+		for j := range plan.Entries {
+			entry := &plan.Entries[j]
+			for _, run := range entry.Runs {
+				if s.run(run.ID) == nil {
+					run.PlanID, run.EntryID, run.ProjectID = plan.ID, entry.ID, plan.ProjectID
+					s.Runs = append(s.Runs, Run{Run: run})
+				}
+			}
+			entry.Runs = nil
+		}
+	}
+}
+
+func (s *State) run(id int) *Run {
+	for i := range s.Runs {
+		if s.Runs[i].ID == id {
+			return &s.Runs[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) plan(id int) *testrail.Plan {
+	for i := range s.Plans {
+		if s.Plans[i].ID == id {
+			return &s.Plans[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) testCase(id int) *testrail.Case {
+	for i := range s.Cases {
+		if s.Cases[i].ID == id {
+			return &s.Cases[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) section(id int) *testrail.Section {
+	for i := range s.Sections {
+		if s.Sections[i].ID == id {
+			return &s.Sections[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) status(id int) *testrail.Status {
+	for i := range s.Statuses {
+		if s.Statuses[i].ID == id {
+			return &s.Statuses[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) user(id int) *testrail.User {
+	for i := range s.Users {
+		if s.Users[i].ID == id {
+			return &s.Users[i]
+		}
+	}
+	return nil
+}
+
+// runCases returns ids of cases included to run
+func (s *State) runCases(run *Run) []int {
+	if !run.IncludeAll {
+		return run.CaseIDs
+	}
+	var ids []int
+	for _, c := range s.Cases {
+		if c.SuiteID == run.SuiteID {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// testIDBase makes fake test ids, testrail test is case added to run, so id of
+// test is derived from run and case ids
+const testIDBase = 1000000
+
+func testID(runID, caseID int) int {
+	return runID*testIDBase + caseID
+}
+
+// withCounts returns run with status counters computed from its latest results
+func (s *State) withCounts(run *Run) testrail.Run {
+	latest := make(map[int]int)
+	for _, r := range s.Results {
+		if r.RunID == run.ID {
+			latest[r.CaseID] = r.StatusID
+		}
+	}
+
+	counted := run.Run
+	counted.PassedCount, counted.FailedCount, counted.BlockedCount, counted.RetestCount, counted.UntestedCount = 0, 0, 0, 0, 0
+	for _, id := range s.runCases(run) {
+		switch latest[id] {
+		case testrail.StatusPassed:
+			counted.PassedCount++
+		case testrail.StatusFailed:
+			counted.FailedCount++
+		case testrail.StatusBlocked:
+			counted.BlockedCount++
+		case testrail.StatusRetest:
+			counted.RetestCount++
+		case 0, testrail.StatusUntested:
+			counted.UntestedCount++
+		}
+	}
+	return counted
+}
+
+func nextID(ids ...int) int {
+	next := 1
+	for _, id := range ids {
+		if id >= next {
+			next = id + 1
+		}
+	}
+	return next
+}
+
+// LoadState reads state from json file, state with defaults is returned if file doesn't exist
+func LoadState(path string) (State, error) {
+	var s State
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return State{}, fmt.Errorf("failed to read state: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s); err != nil {
+			return State{}, fmt.Errorf("failed to unmarshal state %s: %w", path, err)
+		}
+	}
+	s.normalize()
+	return s, nil
+}
+
+// Save writes state to json file, file is replaced atomically
+func (s State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
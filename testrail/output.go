@@ -0,0 +1,90 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	testFramingRe  = regexp.MustCompile(`^=== (RUN|PAUSE|CONT) `)
+	testLocationRe = regexp.MustCompile(`\.go:\d+:`)
+)
+
+const truncatedMark = "...\n"
+
+// OutputBuffer keeps tail of test output limited by size, lines with source location
+// (file.go:NN:) are kept while possible, because they usually point to the failure
+type OutputBuffer struct {
+	limit     int
+	size      int
+	lines     []string
+	truncated bool
+}
+
+func NewOutputBuffer(limit int) *OutputBuffer {
+	return &OutputBuffer{limit: limit}
+}
+
+func (b *OutputBuffer) Add(line string) {
+	if b.limit <= 0 || testFramingRe.MatchString(line) {
+		return
+	}
+
+	if len(line) > b.limit {
+		line = line[len(line)-b.limit:]
+		b.truncated = true
+	}
+
+	b.lines = append(b.lines, line)
+	b.size += len(line)
+
+	for b.size > b.limit {
+		b.dropLine()
+	}
+}
+
+// AddOutput adds lines of output built by another buffer, its truncation mark is kept
+func (b *OutputBuffer) AddOutput(output string) {
+	if strings.HasPrefix(output, truncatedMark) {
+		output = strings.TrimPrefix(output, truncatedMark)
+		b.truncated = true
+	}
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if line != "" {
+			b.Add(line)
+		}
+	}
+}
+
+// dropLine removes oldest line without source location, last line is never dropped
+// in favour of older ones as it is the closest to the failure
+func (b *OutputBuffer) dropLine() {
+	pos := 0
+	for i, l := range b.lines[:len(b.lines)-1] {
+		if !testLocationRe.MatchString(l) {
+			pos = i
+			break
+		}
+	}
+
+	b.size -= len(b.lines[pos])
+	b.lines = append(b.lines[:pos], b.lines[pos+1:]...)
+	b.truncated = true
+}
+
+func (b *OutputBuffer) String() string {
+	if len(b.lines) == 0 {
+		return ""
+	}
+
+	output := strings.Join(b.lines, "")
+	if b.truncated {
+		return truncatedMark + output
+	}
+	return output
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 Insolar Network Ltd.
+// All rights reserved.
+// This material is licensed under the Insolar License version 1.0,
+// available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	t.Run("fits", func(t *testing.T) {
+		b := NewOutputBuffer(100)
+		b.Add("=== RUN   TestExample\n")
+		b.Add("    example_test.go:16: C9999 Pass test\n")
+		b.Add("--- PASS: TestExample (0.10s)\n")
+
+		assert.Equal(t, "    example_test.go:16: C9999 Pass test\n--- PASS: TestExample (0.10s)\n", b.String())
+	})
+
+	t.Run("keeps locations and tail", func(t *testing.T) {
+		b := NewOutputBuffer(60)
+		b.Add("    example_test.go:22: expected 1\n")
+		b.Add("some noise\n")
+		b.Add("more noise\n")
+		b.Add("--- FAIL: TestExample2\n")
+
+		assert.Equal(t, "...\n    example_test.go:22: expected 1\n--- FAIL: TestExample2\n", b.String())
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		b := NewOutputBuffer(5)
+		b.Add("0123456789")
+
+		assert.Equal(t, "...\n56789", b.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		b := NewOutputBuffer(0)
+		b.Add("output\n")
+
+		assert.Equal(t, "", b.String())
+	})
+}
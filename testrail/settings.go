@@ -0,0 +1,209 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/educlos/testrail"
+
+	"github.com/insolar/testrail-cli/types"
+)
+
+func copyStatuses(statuses map[string]int) map[string]int {
+	copied := make(map[string]int, len(statuses))
+	for name, id := range statuses {
+		copied[name] = id
+	}
+	return copied
+}
+
+// ParseStatuses parses space separated test status mapping STATUS=ID_OR_NAME,
+// ex.: "SKIP=skipped N/A=7", test statuses are upper cased
+func ParseStatuses(spec string) (map[string]string, error) {
+	statuses := make(map[string]string)
+	for _, field := range strings.Fields(spec) {
+		i := strings.Index(field, "=")
+		if i <= 0 || i == len(field)-1 {
+			return nil, fmt.Errorf("invalid status mapping %q, use STATUS=ID or STATUS=NAME, ex.: SKIP=6", field)
+		}
+		status := strings.ToUpper(field[:i])
+		if !types.StatusKnown(status) {
+			return nil, fmt.Errorf("unknown test status %s in mapping %q, use %s, %s, %s or %s", field[:i], field,
+				types.TestStatusPassed, types.TestStatusFailed, types.TestStatusSkipped, types.TestStatusNotAvailable)
+		}
+		statuses[status] = field[i+1:]
+	}
+	return statuses, nil
+}
+
+// ResultSettings are assignee and status mapping of results given by ids or by names,
+// names are looked up in testrail
+type ResultSettings struct {
+	Assignee string            `json:"assignee,omitempty"`
+	Statuses map[string]string `json:"statuses,omitempty"`
+}
+
+// UnresolvedSettings are result settings given by names, which weren't looked up because
+// testrail was unavailable, spooled results get them on replay
+type UnresolvedSettings struct {
+	ResultSettings
+	// TestStatuses are test statuses of spooled results by case id
+	TestStatuses map[int]string `json:"test_statuses,omitempty"`
+}
+
+// SetResultIDs applies settings given by ids without requests to testrail, settings given by names
+// are returned and kept until ResolveResults, nil is returned if there are none
+func (m *Uploader) SetResultIDs(s ResultSettings) *ResultSettings {
+	var unresolved ResultSettings
+	if id, err := strconv.Atoi(s.Assignee); err == nil {
+		m.assigneeID = id
+	} else {
+		unresolved.Assignee = s.Assignee
+	}
+	for status, value := range s.Statuses {
+		if id, err := strconv.Atoi(value); err == nil {
+			m.statuses[status] = id
+			continue
+		}
+		if unresolved.Statuses == nil {
+			unresolved.Statuses = make(map[string]string)
+		}
+		unresolved.Statuses[status] = value
+	}
+
+	m.unresolved = nil
+	if unresolved.Assignee != "" || len(unresolved.Statuses) > 0 {
+		m.unresolved = &unresolved
+	}
+	return m.unresolved
+}
+
+// ResolveResults applies settings looking up names in testrail
+func (m *Uploader) ResolveResults(s ResultSettings) error {
+	if s.Assignee != "" {
+		if err := m.ResolveAssignee(s.Assignee); err != nil {
+			return err
+		}
+	}
+	if err := m.ResolveStatuses(s.Statuses); err != nil {
+		return err
+	}
+	m.unresolved = nil
+	return nil
+}
+
+// Unresolved returns settings, which weren't looked up in testrail, with test statuses of pending
+// results, it is nil if all settings are applied
+func (m Uploader) Unresolved() *UnresolvedSettings {
+	if m.unresolved == nil {
+		return nil
+	}
+	u := &UnresolvedSettings{ResultSettings: *m.unresolved, TestStatuses: make(map[int]string)}
+	for _, result := range m.Pending().Results {
+		status, ok := m.testStatuses[result.CaseID]
+		if !ok {
+			// run case without test result marked by mode
+			status = types.TestStatusNotAvailable
+		}
+		u.TestStatuses[result.CaseID] = status
+	}
+	return u
+}
+
+// resolveSpooled looks up settings of spooled results and sets them to results
+func (m *Uploader) resolveSpooled(u UnresolvedSettings) error {
+	if err := m.ResolveResults(u.ResultSettings); err != nil {
+		return err
+	}
+	for caseID, result := range m.tests {
+		if u.Assignee != "" {
+			result.AssignedToID = m.assigneeID
+		}
+		if status := u.TestStatuses[caseID]; u.Statuses[status] != "" {
+			result.StatusID = m.statuses[status]
+		}
+		m.tests[caseID] = result
+	}
+	return nil
+}
+
+// ResolveAssignee sets user results are assigned to by id or email, user is looked up
+// with get_user_by_email
+func (m *Uploader) ResolveAssignee(user string) error {
+	if id, err := strconv.Atoi(user); err == nil {
+		m.assigneeID = id
+		return nil
+	}
+
+	found, err := m.c.GetUserByEmail(user)
+	if err != nil {
+		return fmt.Errorf("failed to find user %s: %w", user, err)
+	}
+	m.assigneeID = found.ID
+	return nil
+}
+
+// SetVersion sets version results are reported for
+func (m *Uploader) SetVersion(version string) {
+	m.version = version
+}
+
+// ResolveStatuses maps test statuses to testrail status ids, status is given by id or by
+// name or label of status from get_statuses, unmapped statuses keep their defaults
+func (m *Uploader) ResolveStatuses(mapping map[string]string) error {
+	var available []testrail.Status
+	for status, value := range mapping {
+		if id, err := strconv.Atoi(value); err == nil {
+			m.statuses[status] = id
+			continue
+		}
+
+		if available == nil {
+			var err error
+			if available, err = m.c.GetStatuses(); err != nil {
+				return fmt.Errorf("failed to get statuses: %w", err)
+			}
+		}
+		id, err := findStatus(available, value)
+		if err != nil {
+			return fmt.Errorf("status %s: %w", status, err)
+		}
+		m.statuses[status] = id
+	}
+	return nil
+}
+
+func findStatus(available []testrail.Status, name string) (int, error) {
+	names := make([]string, 0, len(available))
+	for _, s := range available {
+		if strings.EqualFold(s.Name, name) || strings.EqualFold(s.Label, name) {
+			return s.ID, nil
+		}
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("unknown testrail status %s, available: %s", name, strings.Join(names, ", "))
+}
+
+// StatusName returns test status name for testrail status id
+func (m Uploader) StatusName(statusID int) string {
+	names := make([]string, 0, 1)
+	for name, id := range m.statuses {
+		if id == statusID {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return strconv.Itoa(statusID)
+	}
+	// several test statuses could share testrail status
+	sort.Strings(names)
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,112 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"testing"
+
+	"github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/insolar/testrail-cli/testrail/fake"
+	"github.com/insolar/testrail-cli/types"
+)
+
+func TestParseStatuses(t *testing.T) {
+	statuses, err := ParseStatuses(" skip=skipped  N/A=7 FAIL=Retest ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"SKIP": "skipped", "N/A": "7", "FAIL": "Retest"}, statuses)
+
+	for _, spec := range []string{"SKIP", "=6", "SKIP=", "BLOCKED=Blocked", "PASS=1 NA=7"} {
+		_, err := ParseStatuses(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestUploader_StatusName(t *testing.T) {
+	m := NewUploader("", "", "")
+	assert.Equal(t, types.TestStatusPassed, m.StatusName(testrail.StatusPassed))
+	assert.Equal(t, "3", m.StatusName(testrail.StatusUntested))
+
+	m.statuses[types.TestStatusSkipped] = 7
+	assert.Equal(t, "N/A/SKIP", m.StatusName(7))
+}
+
+func TestUploader_EndToEndSettings(t *testing.T) {
+	state := suiteState()
+	state.Statuses = append(append([]testrail.Status(nil), fake.DefaultStatuses...),
+		testrail.Status{ID: 12, Name: "automation_skipped", Label: "Skipped (auto)"})
+	state.Users = append(state.Users, testrail.User{ID: 3, Email: "ci@example.com", IsActive: true})
+	m, fakeServer, server := newFakeUploader(state)
+	defer server.Close()
+
+	require.NoError(t, m.ResolveAssignee("CI@example.com"))
+	require.NoError(t, m.ResolveStatuses(map[string]string{
+		types.TestStatusSkipped: "skipped (auto)",
+		types.TestStatusFailed:  "4",
+	}))
+	m.SetVersion("v1.2.0-3-gdeadbee")
+
+	require.NoError(t, m.Init(5))
+	m.AddTests([]*types.TestMatcher{
+		{ID: 1, Status: types.TestStatusSkipped, GoTestName: "TestSkipped"},
+		{ID: 2, Status: types.TestStatusFailed, GoTestName: "TestFailed"},
+	}, true)
+	require.NoError(t, m.Upload())
+
+	assert.Equal(t, map[int]int{1: 12, 2: testrail.StatusRetest}, latestStatuses(fakeServer.State(), 5))
+	for _, r := range fakeServer.State().Results {
+		assert.Equal(t, 3, r.AssignedToID)
+		assert.Equal(t, "v1.2.0-3-gdeadbee", r.Version)
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		assert.Error(t, m.ResolveAssignee("nobody@example.com"))
+		err := m.ResolveStatuses(map[string]string{types.TestStatusSkipped: "ignored"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "automation_skipped")
+	})
+}
+
+func TestUploader_EndToEndSpooledSettings(t *testing.T) {
+	state := suiteState()
+	state.Statuses = append(append([]testrail.Status(nil), fake.DefaultStatuses...),
+		testrail.Status{ID: 12, Name: "automation_failed"})
+	state.Users = append(state.Users, testrail.User{ID: 3, Email: "ci@example.com", IsActive: true})
+	m, fakeServer, server := newFakeUploader(state)
+	defer server.Close()
+
+	// testrail is unavailable, names are left to replay
+	unresolved := m.SetResultIDs(ResultSettings{
+		Assignee: "ci@example.com",
+		Statuses: map[string]string{types.TestStatusFailed: "automation_failed", types.TestStatusSkipped: "5"},
+	})
+	require.NotNil(t, unresolved)
+	assert.Equal(t, ResultSettings{
+		Assignee: "ci@example.com",
+		Statuses: map[string]string{types.TestStatusFailed: "automation_failed"},
+	}, *unresolved)
+	m.AddTests(append(suiteTests(), &types.TestMatcher{ID: 3, Status: types.TestStatusSkipped}), false)
+	assert.Equal(t, map[int]string{1: types.TestStatusPassed, 2: types.TestStatusFailed, 3: types.TestStatusSkipped, 9: types.TestStatusPassed},
+		m.Unresolved().TestStatuses)
+
+	entry, err := NewSpoolEntry(server.URL, SpoolRun{RunID: 5}, m.Pending(), false)
+	require.NoError(t, err)
+	entry.Unresolved = m.Unresolved()
+	assert.Equal(t, 0, fakeServer.Calls("get_user_by_email"))
+
+	replay := NewUploader(server.URL, "user", "password")
+	require.NoError(t, replay.InitSpooled(entry))
+	require.NoError(t, replay.Upload())
+	assert.Equal(t, map[int]int{1: testrail.StatusPassed, 2: 12, 3: 5}, latestStatuses(fakeServer.State(), 5))
+	for _, r := range fakeServer.State().Results {
+		assert.Equal(t, 3, r.AssignedToID)
+	}
+
+	assert.Nil(t, replay.SetResultIDs(ResultSettings{Assignee: "3", Statuses: map[string]string{types.TestStatusFailed: "4"}}))
+	assert.Nil(t, replay.Unresolved())
+}
@@ -0,0 +1,189 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/educlos/testrail"
+)
+
+const spoolExt = ".json"
+
+// clockSkew widens period results are checked in, as clocks of cli host and testrail could differ
+const clockSkew = time.Minute
+
+// Ambiguity is results of batches, which failed, but could be added to run anyway
+type Ambiguity struct {
+	CaseIDs []int `json:"case_ids"`
+	// Since is unix time before batches were sent, results added to run since then are checked on replay
+	Since int64 `json:"since"`
+}
+
+// SpoolRun is run spooled results are sent to, if run wasn't known when results were spooled, it is
+// resolved on replay: run of plan entry with Config is used or new run is created in project suite
+type SpoolRun struct {
+	RunID       int    `json:"run_id"`
+	PlanID      int    `json:"plan_id,omitempty"`
+	Config      string `json:"config,omitempty"`
+	ProjectID   int    `json:"project_id,omitempty"`
+	SuiteID     int    `json:"suite_id,omitempty"`
+	Name        string `json:"run_name,omitempty"`
+	MilestoneID int    `json:"milestone_id,omitempty"`
+	// RunAmbiguous is set when run creation failed, but run could be created anyway,
+	// such entry isn't replayed until its run id is set
+	RunAmbiguous bool `json:"run_ambiguous,omitempty"`
+}
+
+func (r SpoolRun) String() string {
+	switch {
+	case r.RunID != 0:
+		return fmt.Sprintf("run %d", r.RunID)
+	case r.PlanID != 0:
+		return fmt.Sprintf("plan %d (%s)", r.PlanID, r.Config)
+	default:
+		return fmt.Sprintf("new run of project %d suite %d", r.ProjectID, r.SuiteID)
+	}
+}
+
+func (r SpoolRun) idPrefix() string {
+	switch {
+	case r.RunID != 0:
+		return fmt.Sprintf("run%d", r.RunID)
+	case r.PlanID != 0:
+		return fmt.Sprintf("plan%d", r.PlanID)
+	default:
+		return fmt.Sprintf("project%d-suite%d", r.ProjectID, r.SuiteID)
+	}
+}
+
+// SpoolEntry is upload postponed because testrail was unreachable
+type SpoolEntry struct {
+	ID string `json:"id"`
+	SpoolRun
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	// Validated is false if results weren't checked against run cases before spooling
+	Validated bool                            `json:"validated"`
+	Metadata  map[string]string               `json:"metadata,omitempty"`
+	Payload   testrail.SendableResultsForCase `json:"payload"`
+	// Ambiguous results are sent on replay only if run has no results of their cases added since
+	Ambiguous *Ambiguity `json:"ambiguous,omitempty"`
+	// Unresolved settings are looked up on replay, as testrail was unavailable when results were spooled
+	Unresolved *UnresolvedSettings `json:"unresolved,omitempty"`
+}
+
+// SpoolFile is spooled entry with path it is stored at
+type SpoolFile struct {
+	Path  string
+	Entry SpoolEntry
+}
+
+// NewSpoolEntry creates entry with id derived from run and payload, so the same results
+// spooled twice are stored in one file
+func NewSpoolEntry(url string, run SpoolRun, payload testrail.SendableResultsForCase, validated bool) (SpoolEntry, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return SpoolEntry{}, fmt.Errorf("failed to marshal spooled results: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	return SpoolEntry{
+		ID:        fmt.Sprintf("%s-%s", run.idPrefix(), hex.EncodeToString(sum[:8])),
+		SpoolRun:  run,
+		URL:       url,
+		CreatedAt: time.Now().UTC(),
+		Validated: validated,
+		Payload:   payload,
+	}, nil
+}
+
+// WriteSpool stores entry in spool directory, file is replaced atomically
+func WriteSpool(dir string, entry SpoolEntry) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create spool dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal spool entry: %w", err)
+	}
+
+	path := filepath.Join(dir, entry.ID+spoolExt)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write spool entry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write spool entry: %w", err)
+	}
+	return path, nil
+}
+
+// ReadSpool returns entries stored in spool directory, oldest first
+func ReadSpool(dir string) ([]SpoolFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+spoolExt))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []SpoolFile
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spool entry: %w", err)
+		}
+
+		file := SpoolFile{Path: path}
+		if err := json.Unmarshal(data, &file.Entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal spool entry %s: %w", path, err)
+		}
+		if file.Entry.ID == "" {
+			file.Entry.ID = strings.TrimSuffix(filepath.Base(path), spoolExt)
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Entry.CreatedAt.Before(files[j].Entry.CreatedAt)
+	})
+	return files, nil
+}
+
+// Save rewrites spooled entry with results which are still pending, file is removed
+// when nothing is left, so delivered results are never replayed again
+func (f *SpoolFile) Save(pending testrail.SendableResultsForCase, ambiguous *Ambiguity) error {
+	if len(pending.Results) == 0 {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove spool entry: %w", err)
+		}
+		return nil
+	}
+
+	f.Entry.Payload = pending
+	f.Entry.Ambiguous = ambiguous
+	return f.write()
+}
+
+// SaveRun rewrites spooled entry with run it is resolved to, so the same run is used if replay is repeated
+func (f *SpoolFile) SaveRun(run SpoolRun) error {
+	f.Entry.SpoolRun = run
+	return f.write()
+}
+
+func (f *SpoolFile) write() error {
+	_, err := WriteSpool(filepath.Dir(f.Path), f.Entry)
+	return err
+}
@@ -0,0 +1,84 @@
+//  Copyright 2020 Insolar Network Ltd.
+//  All rights reserved.
+//  This material is licensed under the Insolar License version 1.0,
+//  available at https://github.com/insolar/testrail-cli/LICENSE.md.
+
+package testrail
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/educlos/testrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	payload := testrail.SendableResultsForCase{Results: []testrail.ResultsForCase{
+		{CaseID: 1, SendableResult: testrail.SendableResult{StatusID: testrail.StatusPassed}},
+		{CaseID: 2, SendableResult: testrail.SendableResult{StatusID: testrail.StatusFailed}},
+	}}
+	entry, err := NewSpoolEntry("https://testrail", SpoolRun{RunID: 54}, payload, true)
+	require.NoError(t, err)
+
+	// the same results are spooled to the same file
+	_, err = WriteSpool(dir, entry)
+	require.NoError(t, err)
+	_, err = WriteSpool(dir, entry)
+	require.NoError(t, err)
+
+	files, err := ReadSpool(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, entry.ID, files[0].Entry.ID)
+	assert.Equal(t, 54, files[0].Entry.RunID)
+	assert.Len(t, files[0].Entry.Payload.Results, 2)
+
+	require.NoError(t, files[0].Save(testrail.SendableResultsForCase{Results: payload.Results[1:]}, &Ambiguity{CaseIDs: []int{2}, Since: 1592388000}))
+	files, err = ReadSpool(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Len(t, files[0].Entry.Payload.Results, 1)
+	assert.Equal(t, 2, files[0].Entry.Payload.Results[0].CaseID)
+	assert.Equal(t, &Ambiguity{CaseIDs: []int{2}, Since: 1592388000}, files[0].Entry.Ambiguous)
+
+	require.NoError(t, files[0].Save(testrail.SendableResultsForCase{}, nil))
+	files, err = ReadSpool(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSpool_UnresolvedRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	payload := testrail.SendableResultsForCase{Results: []testrail.ResultsForCase{{CaseID: 1}}}
+	entry, err := NewSpoolEntry("https://testrail", SpoolRun{PlanID: 60, Config: "MySQL"}, payload, false)
+	require.NoError(t, err)
+	assert.Regexp(t, "^plan60-", entry.ID)
+	path, err := WriteSpool(dir, entry)
+	require.NoError(t, err)
+
+	files, err := ReadSpool(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, SpoolRun{PlanID: 60, Config: "MySQL"}, files[0].Entry.SpoolRun)
+
+	require.NoError(t, files[0].SaveRun(SpoolRun{RunID: 7, PlanID: 60, Config: "MySQL"}))
+	files, err = ReadSpool(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, path, files[0].Path)
+	assert.Equal(t, 7, files[0].Entry.RunID)
+
+	entry, err = NewSpoolEntry("https://testrail", SpoolRun{ProjectID: 1, SuiteID: 2}, payload, false)
+	require.NoError(t, err)
+	assert.Regexp(t, "^project1-suite2-", entry.ID)
+}
@@ -13,7 +13,13 @@ type Converter interface {
 	// ConvertEventsToMatcherObjectsPreload parses event batches to construct TestObjects, extracting caseID, Description, Status and IssueURL
 	ConvertEventsToMatcherObjectsPreload(events map[string][]parser.TestEvent) []*TestMatcher
 	// ConvertEventsToMatcherObjects parses event stream to construct TestObject, extracting caseID, Description, Status and IssueURL
-	ConvertEventsToMatcherObjects(reader parser.EventReader)[]*TestMatcher
+	ConvertEventsToMatcherObjects(reader parser.EventReader) []*TestMatcher
+}
+
+// CaseRef is testrail case logged by test, ex.: t.Log("C3605 Some testcase description")
+type CaseRef struct {
+	ID          int    `json:"id"`
+	Description string `json:"title,omitempty"`
 }
 
 // TestMatcher represents data differences between implementation and testrail case
@@ -23,5 +29,30 @@ type TestMatcher struct {
 	Description         string
 	OriginalDescription string
 	GoTestName          string
-	IssueURL            string
+	Package             string
+	// IssueURL is issue reference built by issue pattern, it is sent as result defects
+	IssueURL string
+	Elapsed  float64 // seconds
+	Output   string
+	// Cases lists all cases logged by test in order of appearance
+	Cases []CaseRef
+	// Ambiguous lists output lines with several case references or case logged with another title
+	Ambiguous []string
+}
+
+// SplitCases returns matcher per case logged by test, so every case gets its own result
+// with test status, matcher without cases is returned as is
+func (t *TestMatcher) SplitCases() []*TestMatcher {
+	if len(t.Cases) == 0 {
+		return []*TestMatcher{t}
+	}
+
+	matchers := make([]*TestMatcher, 0, len(t.Cases))
+	for _, ref := range t.Cases {
+		m := *t
+		m.ID = ref.ID
+		m.Description = ref.Description
+		matchers = append(matchers, &m)
+	}
+	return matchers
 }
@@ -8,6 +8,7 @@ package types
 type TestCaseWithDescription struct {
 	ID          int
 	Description string
+	SectionID   int
 }
 
 type TestCasesWithDescription []TestCaseWithDescription
@@ -22,10 +23,10 @@ const (
 type TestServer interface {
 	FormatURL(id int) string
 
-	Init(runID int)
+	Init(runID int) error
 	GetCasesWithDescription() TestCasesWithDescription
-	AddTests(objects []*TestMatcher)
-	Upload()
+	AddTests(objects []*TestMatcher, ignoreNonExistent bool)
+	Upload() error
 }
 
 func StatusKnown(status string) bool {